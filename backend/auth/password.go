@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	hashAlgorithm  = "pbkdf2-sha256"
+	hashIterations = 210000
+	saltLength     = 16
+	keyLength      = 32
+)
+
+var errInvalidHash = errors.New("invalid password hash")
+
+// HashPassword returns the salted PBKDF2-SHA256 hash of the password, as algorithm$iterations$salt$key
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := pbkdf2SHA256([]byte(password), salt, hashIterations, keyLength)
+	return strings.Join([]string{hashAlgorithm, strconv.Itoa(hashIterations),
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)}, "$"), nil
+}
+
+// CheckPassword returns true if the password matches the hash
+func CheckPassword(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashAlgorithm {
+		return false, errInvalidHash
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, errInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errInvalidHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return false, errInvalidHash
+	}
+	return hmac.Equal(key, pbkdf2SHA256([]byte(password), salt, iterations, len(key))), nil
+}
+
+// pbkdf2SHA256 is PBKDF2 (RFC 8018) with HMAC-SHA256
+func pbkdf2SHA256(password, salt []byte, iterations, length int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	u := make([]byte, 0, prf.Size())
+	block := make([]byte, prf.Size())
+	for i := uint32(1); len(key) < length; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, i))
+		u = prf.Sum(u[:0])
+		copy(block, u)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range block {
+				block[j] ^= u[j]
+			}
+		}
+		key = append(key, block...)
+	}
+	return key[:length]
+}
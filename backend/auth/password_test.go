@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		name           string
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		//RFC 7914, section 11
+		{name: "rfc 7914 one iteration", password: "passwd", salt: "salt", iterations: 1,
+			want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{name: "rfc 7914 80000 iterations", password: "Password", salt: "NaCl", iterations: 80000,
+			want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+		//shorter than a block
+		{name: "truncated", password: "password", salt: "salt", iterations: 4096,
+			want: "c5e478d59288c841aa530db6845c4c8d962893a0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, _ := hex.DecodeString(tt.want)
+			got := pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, len(want))
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("pbkdf2SHA256 = %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("secretpass")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$210000$") {
+		t.Errorf("hash %s, want the algorithm and the iterations first", hash)
+	}
+	if other, _ := HashPassword("secretpass"); other == hash {
+		t.Error("two hashes of the same password are equal, the salt is not random")
+	}
+	if ok, err := CheckPassword(hash, "secretpass"); err != nil || !ok {
+		t.Errorf("CheckPassword with the right password = %v, %v", ok, err)
+	}
+	if ok, err := CheckPassword(hash, "secretpasS"); err != nil || ok {
+		t.Errorf("CheckPassword with a wrong password = %v, %v", ok, err)
+	}
+}
+
+func TestCheckPasswordInvalidHash(t *testing.T) {
+	tests := []string{
+		"",
+		"bcrypt$10$c2FsdA$a2V5",
+		"pbkdf2-sha256$0$c2FsdA$a2V5",
+		"pbkdf2-sha256$many$c2FsdA$a2V5",
+		"pbkdf2-sha256$1$!$a2V5",
+		"pbkdf2-sha256$1$c2FsdA$",
+	}
+	for _, hash := range tests {
+		if _, err := CheckPassword(hash, "password"); !errors.Is(err, errInvalidHash) {
+			t.Errorf("CheckPassword(%q) error = %v, want %v", hash, err, errInvalidHash)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Session is a login of a user
+type Session struct {
+	Username string    `json:"username"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Sessions are the logins, kept in memory: a restart logs out everybody
+type Sessions struct {
+	mu       sync.Mutex
+	duration time.Duration
+	//sessions are indexed by the hash of the token, not by the token itself
+	sessions map[string]Session
+}
+
+// NewSessions returns the sessions lasting duration from the login
+func NewSessions(duration time.Duration) *Sessions {
+	return &Sessions{duration: duration, sessions: make(map[string]Session)}
+}
+
+// Create starts a session and returns its token
+func (s *Sessions) Create(username string) (string, Session, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", Session{}, err
+	}
+	token := hex.EncodeToString(b)
+	session := Session{Username: username, Expires: time.Now().Add(s.duration)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeExpired()
+	s.sessions[tokenKey(token)] = session
+	return token, session, nil
+}
+
+// Get returns the session of a token, if it is not expired
+func (s *Sessions) Get(token string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[tokenKey(token)]
+	if !ok || time.Now().After(session.Expires) {
+		return Session{}, false
+	}
+	return session, true
+}
+
+// Delete ends a session
+func (s *Sessions) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, tokenKey(token))
+}
+
+// DeleteUser ends all the sessions of a user but the one of keepToken, that can be empty
+func (s *Sessions) DeleteUser(username, keepToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keep := tokenKey(keepToken)
+	for key, session := range s.sessions {
+		if session.Username == username && (keepToken == "" || key != keep) {
+			delete(s.sessions, key)
+		}
+	}
+}
+
+func (s *Sessions) removeExpired() {
+	now := time.Now()
+	for key, session := range s.sessions {
+		if now.After(session.Expires) {
+			delete(s.sessions, key)
+		}
+	}
+}
+
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	//freeLoginFailures are the failures of an address before it has to wait
+	freeLoginFailures = 3
+	//loginFailuresReset is the time without failures after which an address starts again
+	loginFailuresReset = 15 * time.Minute
+)
+
+type loginAttempts struct {
+	failures    int
+	inProgress  bool
+	lastFailure time.Time
+	retryAt     time.Time
+}
+
+// LoginThrottle slows down the password guessing: after some failures an address waits before trying again,
+// double the time at each new failure, and it can check one password at a time
+type LoginThrottle struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	maxDelay  time.Duration
+	addresses map[string]*loginAttempts
+	now       func() time.Time
+}
+
+// NewLoginThrottle returns a throttle waiting delay after the first failures that are not free, up to maxDelay
+func NewLoginThrottle(delay, maxDelay time.Duration) *LoginThrottle {
+	return &LoginThrottle{delay: delay, maxDelay: maxDelay, addresses: make(map[string]*loginAttempts), now: time.Now}
+}
+
+// Start returns 0 if the address can try a login, that must then be ended with Done, or how long it must wait
+func (t *LoginThrottle) Start(address string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	a, ok := t.addresses[address]
+	if !ok {
+		a = &loginAttempts{}
+		t.addresses[address] = a
+	}
+	if a.failures > 0 && now.Sub(a.lastFailure) > loginFailuresReset {
+		*a = loginAttempts{inProgress: a.inProgress}
+	}
+	if wait := a.retryAt.Sub(now); wait > 0 {
+		return wait
+	}
+	if a.inProgress {
+		//another check is running, it is a short wait
+		return time.Second
+	}
+	a.inProgress = true
+	return 0
+}
+
+// Done ends the login started, a failure makes the address wait if it had too many
+func (t *LoginThrottle) Done(address string, succeeded bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a, ok := t.addresses[address]
+	if !ok {
+		return
+	}
+	a.inProgress = false
+	if succeeded {
+		delete(t.addresses, address)
+		return
+	}
+	now := t.now()
+	a.failures++
+	a.lastFailure = now
+	if a.failures >= freeLoginFailures {
+		delay := t.delay << min(a.failures-freeLoginFailures, 16)
+		a.retryAt = now.Add(min(delay, t.maxDelay))
+	}
+	t.removeForgotten(now)
+}
+
+// removeForgotten removes the addresses whose failures are old enough to be reset
+func (t *LoginThrottle) removeForgotten(now time.Time) {
+	for address, a := range t.addresses {
+		if !a.inProgress && now.Sub(a.lastFailure) > loginFailuresReset && now.After(a.retryAt) {
+			delete(t.addresses, address)
+		}
+	}
+}
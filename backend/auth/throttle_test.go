@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestThrottle() (*LoginThrottle, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	t := NewLoginThrottle(time.Second, time.Minute)
+	t.now = func() time.Time { return now }
+	return t, &now
+}
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	throttle, now := newTestThrottle()
+	tests := []struct {
+		failure int
+		want    time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{8, 32 * time.Second},
+		{9, time.Minute},
+		{20, time.Minute},
+	}
+	failures := 0
+	for _, tt := range tests {
+		for ; failures < tt.failure; failures++ {
+			if wait := throttle.Start("10.0.0.1"); wait > 0 {
+				*now = now.Add(wait)
+				if wait := throttle.Start("10.0.0.1"); wait != 0 {
+					t.Fatalf("Start after the wait = %v", wait)
+				}
+			}
+			throttle.Done("10.0.0.1", false)
+		}
+		if wait := max(0, throttle.addresses["10.0.0.1"].retryAt.Sub(*now)); wait != tt.want {
+			t.Errorf("wait after %d failures = %v, want %v", tt.failure, wait, tt.want)
+		}
+	}
+	if wait := throttle.Start("10.0.0.2"); wait != 0 {
+		t.Errorf("another address waits %v", wait)
+	}
+}
+
+func TestLoginThrottleReset(t *testing.T) {
+	throttle, now := newTestThrottle()
+	for range freeLoginFailures {
+		throttle.Start("10.0.0.1")
+		throttle.Done("10.0.0.1", false)
+	}
+	if wait := throttle.Start("10.0.0.1"); wait == 0 {
+		t.Fatal("no wait after the free failures")
+	}
+	*now = now.Add(loginFailuresReset + time.Second)
+	if wait := throttle.Start("10.0.0.1"); wait != 0 {
+		t.Errorf("wait after the reset time = %v", wait)
+	}
+	throttle.Done("10.0.0.1", false)
+	if wait := throttle.Start("10.0.0.1"); wait != 0 {
+		t.Errorf("a failure after the reset is free, wait = %v", wait)
+	}
+	throttle.Done("10.0.0.1", true)
+	if len(throttle.addresses) != 0 {
+		t.Errorf("addresses kept after a login: %v", throttle.addresses)
+	}
+}
+
+func TestLoginThrottleOneAtATime(t *testing.T) {
+	throttle, _ := newTestThrottle()
+	if wait := throttle.Start("10.0.0.1"); wait != 0 {
+		t.Fatalf("first Start = %v", wait)
+	}
+	if wait := throttle.Start("10.0.0.1"); wait == 0 {
+		t.Error("a second login from the address while the first is checked is allowed")
+	}
+	throttle.Done("10.0.0.1", false)
+	if wait := throttle.Start("10.0.0.1"); wait != 0 {
+		t.Errorf("Start after the first is done = %v", wait)
+	}
+}
@@ -0,0 +1,287 @@
+// Package auth holds the local user accounts of the oven and their sessions.
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Role is what a user can do, each role can do everything the previous ones can
+type Role string
+
+const (
+	//Viewer can only read the oven status, the programs and the runs
+	Viewer Role = "viewer"
+	//Operator can also start and stop the programs and actuate the oven
+	Operator Role = "operator"
+	//Admin can also edit the configuration and the programs, manage the users and power off
+	Admin Role = "admin"
+)
+
+var roles = []Role{Viewer, Operator, Admin}
+
+// ParseRole checks a role name
+func ParseRole(s string) (Role, error) {
+	if !slices.Contains(roles, Role(s)) {
+		return "", fmt.Errorf("%w %s", ErrUnknownRole, s)
+	}
+	return Role(s), nil
+}
+
+// Allows returns true if the role can do what the required one can
+func (r Role) Allows(required Role) bool {
+	return slices.Index(roles, r) >= slices.Index(roles, required)
+}
+
+// MinPasswordLength is the shortest password accepted
+const MinPasswordLength = 8
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrLastAdmin          = errors.New("the last admin cannot be removed or demoted")
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrPasswordTooShort   = fmt.Errorf("password shorter than %d characters", MinPasswordLength)
+)
+
+// User is an account, without its password
+type User struct {
+	Username string    `json:"username"`
+	Role     Role      `json:"role"`
+	Created  time.Time `json:"created"`
+}
+
+// storedUser is an account as saved in the users file
+type storedUser struct {
+	User
+	PasswordHash string `json:"password-hash"`
+}
+
+// Store is the list of the users, saved in a JSON file readable only by the owner
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	users []storedUser
+}
+
+// NewStore reads the users file, a missing file is an empty store
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.users); err != nil {
+		return nil, fmt.Errorf("cannot read users file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Empty returns true if there are no users
+func (s *Store) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users) == 0
+}
+
+// Users returns all the users, sorted by name
+func (s *Store) Users() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, len(s.users))
+	for i, u := range s.users {
+		users[i] = u.User
+	}
+	slices.SortFunc(users, func(a, b User) int { return strings.Compare(a.Username, b.Username) })
+	return users
+}
+
+// Get returns a user
+func (s *Store) Get(username string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(username)
+	if i < 0 {
+		return User{}, ErrUserNotFound
+	}
+	return s.users[i].User, nil
+}
+
+// Authenticate returns the user if the password is right
+func (s *Store) Authenticate(username, password string) (User, error) {
+	s.mu.Lock()
+	i := s.index(username)
+	var u storedUser
+	if i >= 0 {
+		u = s.users[i]
+	}
+	s.mu.Unlock()
+	if i < 0 {
+		//hash anyway, not to tell which usernames exist by the response time
+		HashPassword(password)
+		return User{}, ErrInvalidCredentials
+	}
+	ok, err := CheckPassword(u.PasswordHash, password)
+	if err != nil {
+		return User{}, err
+	}
+	if !ok {
+		return User{}, ErrInvalidCredentials
+	}
+	return u.User, nil
+}
+
+// Add creates a user
+func (s *Store) Add(username, password string, role Role) (User, error) {
+	if err := validateUsername(username); err != nil {
+		return User{}, err
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return User{}, err
+	}
+	hash, err := hashValidPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index(username) >= 0 {
+		return User{}, ErrUserExists
+	}
+	u := storedUser{User: User{Username: username, Role: role, Created: time.Now()}, PasswordHash: hash}
+	users := append(slices.Clone(s.users), u)
+	if err := s.save(users); err != nil {
+		return User{}, err
+	}
+	return u.User, nil
+}
+
+// SetPassword changes the password of a user
+func (s *Store) SetPassword(username, password string) error {
+	hash, err := hashValidPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.update(username, func(u *storedUser) { u.PasswordHash = hash })
+}
+
+// Update changes the role and the password of a user, keeping the ones empty. Both are checked before saving
+// them together, the last admin cannot be demoted.
+func (s *Store) Update(username, password string, role Role) (User, error) {
+	if role != "" {
+		if _, err := ParseRole(string(role)); err != nil {
+			return User{}, err
+		}
+	}
+	var hash string
+	if password != "" {
+		var err error
+		if hash, err = hashValidPassword(password); err != nil {
+			return User{}, err
+		}
+	}
+	var updated User
+	err := s.update(username, func(u *storedUser) {
+		if role != "" {
+			u.Role = role
+		}
+		if hash != "" {
+			u.PasswordHash = hash
+		}
+		updated = u.User
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return updated, nil
+}
+
+// Delete removes a user, the last admin cannot be removed
+func (s *Store) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(username)
+	if i < 0 {
+		return ErrUserNotFound
+	}
+	users := slices.Delete(slices.Clone(s.users), i, i+1)
+	if s.users[i].Role == Admin && !hasAdmin(users) {
+		return ErrLastAdmin
+	}
+	return s.save(users)
+}
+
+// update changes a copy of a user and saves it, if an admin is still left
+func (s *Store) update(username string, change func(u *storedUser)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(username)
+	if i < 0 {
+		return ErrUserNotFound
+	}
+	users := slices.Clone(s.users)
+	change(&users[i])
+	if hasAdmin(s.users) && !hasAdmin(users) {
+		return ErrLastAdmin
+	}
+	return s.save(users)
+}
+
+func (s *Store) index(username string) int {
+	return slices.IndexFunc(s.users, func(u storedUser) bool { return u.Username == username })
+}
+
+// save writes the users to a temporary file renamed over the old one, then keeps them
+func (s *Store) save(users []storedUser) error {
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.users = users
+	return nil
+}
+
+func hasAdmin(users []storedUser) bool {
+	return slices.ContainsFunc(users, func(u storedUser) bool { return u.Role == Admin })
+}
+
+func validateUsername(username string) error {
+	if username == "" || len(username) > 64 || strings.ContainsFunc(username, func(r rune) bool {
+		return r <= ' ' || r == '/' || r == ':'
+	}) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+func hashValidPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	return HashPassword(password)
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if _, err := s.Add("boss", "secretpass", Admin); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := s.Add("vic", "viewpass1", Viewer); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	tests := []struct {
+		name     string
+		username string
+		password string
+		role     Role
+		wantErr  error
+	}{
+		{name: "short password with a role", username: "vic", password: "short", role: Operator, wantErr: ErrPasswordTooShort},
+		{name: "unknown role with a password", username: "vic", password: "newpass12", role: "root", wantErr: ErrUnknownRole},
+		{name: "last admin demoted with a password", username: "boss", password: "newpass12", role: Viewer, wantErr: ErrLastAdmin},
+		{name: "unknown user", username: "nobody", role: Viewer, wantErr: ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Update(tt.username, tt.password, tt.role); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update error = %v, want %v", err, tt.wantErr)
+			}
+			//nothing is changed, in memory and in the file
+			reread, err := NewStore(path)
+			if err != nil {
+				t.Fatalf("NewStore: %v", err)
+			}
+			for _, store := range []*Store{s, reread} {
+				if u, _ := store.Get("vic"); u.Role != Viewer {
+					t.Errorf("vic role = %s, want %s", u.Role, Viewer)
+				}
+				if u, _ := store.Get("boss"); u.Role != Admin {
+					t.Errorf("boss role = %s, want %s", u.Role, Admin)
+				}
+				if _, err := store.Authenticate("boss", "secretpass"); err != nil {
+					t.Errorf("boss password changed: %v", err)
+				}
+			}
+		})
+	}
+
+	u, err := s.Update("vic", "newpass12", Operator)
+	if err != nil || u.Role != Operator {
+		t.Fatalf("Update = %+v, %v", u, err)
+	}
+	if _, err := s.Authenticate("vic", "newpass12"); err != nil {
+		t.Errorf("Authenticate with the new password: %v", err)
+	}
+	if u, err := s.Update("vic", "", Viewer); err != nil || u.Role != Viewer {
+		t.Errorf("Update of the role only = %+v, %v", u, err)
+	}
+	if _, err := s.Authenticate("vic", "newpass12"); err != nil {
+		t.Errorf("the password changed with the role only: %v", err)
+	}
+}
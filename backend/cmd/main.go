@@ -1,25 +1,103 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
+	"fmt"
 	"log/slog"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-chi/httplog/v2"
+	"github.com/idalmasso/ovencontrol/backend/auth"
+	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/dummyinterface"
 	"github.com/idalmasso/ovencontrol/backend/hwinterface"
+	"github.com/idalmasso/ovencontrol/backend/runfile"
 	"github.com/idalmasso/ovencontrol/backend/server"
 )
 
-func init() {
-	flag.Parse()
+const (
+	backendHardware   = "hw"
+	backendSimulation = "sim"
+)
+
+// Every flag can also be set with its environment variable, the flag wins if both are set
+var (
+	backend           = flag.String("backend", envOrDefault("OVEN_BACKEND", backendHardware), "controller backend: hw (raspberry) or sim (simulator) [OVEN_BACKEND]")
+	configFile        = flag.String("config", envOrDefault("OVEN_CONFIG", "configuration.yaml"), "configuration file [OVEN_CONFIG]")
+	programsDirectory = flag.String("programs-dir", envOrDefault("OVEN_PROGRAMS_DIR", ""), "oven programs folder, overrides the configuration [OVEN_PROGRAMS_DIR]")
+	runsDirectory     = flag.String("runs-dir", envOrDefault("OVEN_RUNS_DIR", ""), "saved runs folder, overrides the configuration [OVEN_RUNS_DIR]")
+	listenAddress     = flag.String("listen", envOrDefault("OVEN_LISTEN", ""), "listen address, as :8080, overrides the configuration port [OVEN_LISTEN]")
+	logLevel          = flag.String("log-level", envOrDefault("OVEN_LOG_LEVEL", "info"), "log level: debug, info, warn or error [OVEN_LOG_LEVEL]")
+	logFormat         = flag.String("log-format", envOrDefault("OVEN_LOG_FORMAT", "text"), "log format: text or json [OVEN_LOG_FORMAT]")
+	migrateRuns       = flag.Bool("migrate-runs", false, "rewrite the saved runs in the current run file format, keeping the originals, then exit")
+	setUser           = flag.String("set-user", "", "create or update a user, with the password read from OVEN_USER_PASSWORD or from the standard input, then exit")
+	userRole          = flag.String("user-role", string(auth.Admin), "role of the user of -set-user: viewer, operator or admin")
+)
+
+func envOrDefault(name, defaultValue string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return defaultValue
+}
 
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelDebug, fmt.Errorf("invalid log level %s", level)
+	}
+	return l, nil
+}
+
+// setUserAccount adds the user or changes its password and role
+func setUserAccount(usersFile, username, roleName string) error {
+	role, err := auth.ParseRole(roleName)
+	if err != nil {
+		return err
+	}
+	password, ok := os.LookupEnv("OVEN_USER_PASSWORD")
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Password for %s: ", username)
+		password, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			return fmt.Errorf("cannot read the password: %w", err)
+		}
+		password = strings.TrimRight(password, "\r\n")
+	}
+	users, err := auth.NewStore(usersFile)
+	if err != nil {
+		return err
+	}
+	if _, err := users.Get(username); errors.Is(err, auth.ErrUserNotFound) {
+		_, err = users.Add(username, password, role)
+		return err
+	}
+	if password == "" {
+		return auth.ErrPasswordTooShort
+	}
+	_, err = users.Update(username, password, role)
+	return err
 }
 
 func main() {
+	flag.Parse()
 	slog.Info("backend start process")
+	level, err := parseLogLevel(*logLevel)
+	if err != nil {
+		slog.Error("Error", "error", err)
+		os.Exit(2)
+	}
+	if *logFormat != "text" && *logFormat != "json" {
+		slog.Error("Error", "error", "invalid log format "+*logFormat)
+		os.Exit(2)
+	}
 	logger := httplog.NewLogger("oven-logger", httplog.Options{
-		// JSON:             true,
-		LogLevel:         slog.LevelDebug,
+		JSON:             *logFormat == "json",
+		LogLevel:         level,
 		Concise:          true,
 		RequestHeaders:   true,
 		MessageFieldName: "message",
@@ -33,10 +111,56 @@ func main() {
 		QuietDownPeriod: 10 * time.Second,
 		// SourceFieldName: "source",
 	})
-	controller := hwinterface.NewController(hwinterface.WithLogger(logger))
-	//controller := dummyinterface.NewDummyController(dummyinterface.WithLogger(logger))
+	configuration := config.Config{}
+	if err := configuration.ReadFromFile(*configFile); err != nil {
+		logger.Error("Error", "error", err)
+		panic("cannot read configuration file")
+	}
+
+	if *migrateRuns {
+		folder := configuration.Controller.SavedRunFolder
+		if *runsDirectory != "" {
+			folder = *runsDirectory
+		}
+		migrated, err := runfile.MigrateFolder(folder)
+		for _, file := range migrated {
+			logger.Info("Run migrated", "file", file, "backup", file+runfile.BackupExtension)
+		}
+		if err != nil {
+			logger.Error("Error", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Runs migrated", "folder", folder, "count", len(migrated))
+		return
+	}
+
+	if *setUser != "" {
+		if err := setUserAccount(configuration.Auth.UsersFile, *setUser, *userRole); err != nil {
+			logger.Error("Error", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("User saved", "username", *setUser, "role", *userRole, "users-file", configuration.Auth.UsersFile)
+		return
+	}
+
+	var controller server.Machine
+	switch strings.ToLower(*backend) {
+	case backendHardware:
+		controller, err = hwinterface.NewController(hwinterface.WithLogger(logger), hwinterface.WithConfig(configuration))
+		if err != nil {
+			logger.Error("Error", "error", err)
+			panic("cannot create the controller")
+		}
+	case backendSimulation:
+		controller = dummyinterface.NewDummyController(dummyinterface.WithLogger(logger))
+	default:
+		logger.Error("Error", "error", "invalid backend "+*backend)
+		os.Exit(2)
+	}
+	logger.Info("Controller created", "backend", *backend, "config", *configFile)
 
-	server := server.NewMachineServer()
+	server := server.NewMachineServer(server.WithConfigFile(*configFile), server.WithListenAddress(*listenAddress),
+		server.WithDataDirectories(*programsDirectory, *runsDirectory))
 	server.Init(controller, logger)
 	server.ListenAndServe()
 
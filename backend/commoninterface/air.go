@@ -0,0 +1,11 @@
+package commoninterface
+
+// AirPosition is the position of the air damper
+type AirPosition string
+
+const (
+	AirUnknown AirPosition = "unknown"
+	AirOpen    AirPosition = "open"
+	AirClosed  AirPosition = "closed"
+	AirMoving  AirPosition = "moving"
+)
@@ -0,0 +1,10 @@
+package commoninterface
+
+import "time"
+
+// Alarm is an abnormal condition detected by the oven, that the operator should know about
+type Alarm struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Message string    `json:"message"`
+}
@@ -0,0 +1,27 @@
+package commoninterface
+
+import "time"
+
+// DiagnosticStatus is the result of a diagnostic check
+type DiagnosticStatus string
+
+const (
+	DiagnosticPass    DiagnosticStatus = "pass"
+	DiagnosticWarn    DiagnosticStatus = "warn"
+	DiagnosticFail    DiagnosticStatus = "fail"
+	DiagnosticSkipped DiagnosticStatus = "skipped"
+)
+
+// DiagnosticCheck is one check of the diagnostics
+type DiagnosticCheck struct {
+	Name    string           `json:"name"`
+	Status  DiagnosticStatus `json:"status"`
+	Message string           `json:"message"`
+}
+
+// DiagnosticsReport is the result of the self test
+type DiagnosticsReport struct {
+	Time   time.Time         `json:"time"`
+	Checks []DiagnosticCheck `json:"checks"`
+	Passed bool              `json:"passed"`
+}
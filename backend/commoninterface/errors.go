@@ -0,0 +1,13 @@
+package commoninterface
+
+import "errors"
+
+// ErrSafetyTrip is returned by an oven when a safety limit has been reached and the power has been cut.
+// A program receiving it must stop.
+var ErrSafetyTrip = errors.New("safety trip")
+
+// ErrNotSupported is returned when a reading is not available on the device, as the cold junction on a MAX6675
+var ErrNotSupported = errors.New("not supported by this device")
+
+// ErrLidOpenTimeout is returned when a paused program cannot resume because the lid stayed open too long
+var ErrLidOpenTimeout = errors.New("lid open timeout")
@@ -0,0 +1,23 @@
+package commoninterface
+
+// IndicatorState is the oven state shown by the status LEDs and the buzzer
+type IndicatorState string
+
+const (
+	IndicatorIdle    IndicatorState = "idle"
+	IndicatorHeating IndicatorState = "heating"
+	IndicatorHolding IndicatorState = "holding"
+	IndicatorCooling IndicatorState = "cooling"
+	IndicatorPaused  IndicatorState = "paused"
+	IndicatorFault   IndicatorState = "fault"
+	//IndicatorWaiting is shown while a program waits for its scheduled start
+	IndicatorWaiting IndicatorState = "waiting"
+	//IndicatorDiagnostics is shown while the diagnostics run, the oven cannot be used yet
+	IndicatorDiagnostics IndicatorState = "diagnostics"
+	IndicatorFinished    IndicatorState = "finished"
+)
+
+// NeedsAcknowledge returns true for the states that keep signaling until the operator acknowledges them
+func (s IndicatorState) NeedsAcknowledge() bool {
+	return s == IndicatorFinished || s == IndicatorFault
+}
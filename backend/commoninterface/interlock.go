@@ -0,0 +1,7 @@
+package commoninterface
+
+// InterlockState is the state of the physical safety inputs of the oven
+type InterlockState struct {
+	EmergencyStop bool `json:"emergency-stop"`
+	LidOpen       bool `json:"lid-open"`
+}
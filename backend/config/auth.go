@@ -0,0 +1,24 @@
+package config
+
+// AuthConfig sets the user accounts needed to use the API. It is read at start, changes need a restart.
+type AuthConfig struct {
+	//Enabled requires a login for every API call, when false everybody can do everything
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	//UsersFile holds the accounts with their hashed passwords
+	UsersFile string `yaml:"usersFile" json:"users-file"`
+	//SessionHours is how long a login lasts
+	SessionHours float64 `yaml:"sessionHours" json:"session-hours,string"`
+	//AllowedOrigins are the other origins that can call the API from a browser, as http://localhost:3000 for the
+	//frontend development server. When empty only the pages served by the oven itself can call it.
+	AllowedOrigins []string `yaml:"allowedOrigins" json:"allowed-origins"`
+}
+
+// DefaultAuthConfig requires a login and allows only the origin the frontend is served from.
+// Nobody can log in until an admin is added with -set-user.
+func DefaultAuthConfig() AuthConfig {
+	return AuthConfig{
+		Enabled:      true,
+		UsersFile:    "users.json",
+		SessionHours: 12,
+	}
+}
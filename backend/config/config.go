@@ -36,7 +36,55 @@ type Config struct {
 		SavedRunFolder    string  `yaml:"savedRunFolder" json:"saved-run-folder"`
 		UsbPath           string  `yaml:"usbPath" json:"usb-path"`
 		UsbSaveFolderName string  `yaml:"usbSaveFolderName" json:"usb-save-folder-name"`
+		//LidOpenTimeoutSeconds is how long a program stays paused with the lid open before it is aborted
+		LidOpenTimeoutSeconds float64 `yaml:"lidOpenTimeoutSeconds" json:"lid-open-timeout-seconds,string"`
+		//EventLogFile is the log of the events of all the runs, each run has also its own next to its data
+		EventLogFile string `yaml:"eventLogFile" json:"event-log-file"`
+		//Filter is applied to the temperature before the controller
+		Filter struct {
+			//Type is none (default), median, ema or kalman
+			Type             string  `yaml:"type" json:"type"`
+			Window           int     `yaml:"window" json:"window,string"`
+			Alpha            float64 `yaml:"alpha" json:"alpha,string"`
+			ProcessNoise     float64 `yaml:"processNoise" json:"process-noise,string"`
+			MeasurementNoise float64 `yaml:"measurementNoise" json:"measurement-noise,string"`
+		} `yaml:"filter" json:"filter"`
 	} `yaml:"controller" json:"controller"`
+	//Sensors are the thermocouple readers, each one on its own chip select
+	Sensors []SensorConfig `yaml:"sensors" json:"sensors"`
+	//SensorVoting is how the readings of more sensors are combined in the oven temperature
+	SensorVoting struct {
+		//Policy is mean (default), max or median
+		Policy string `yaml:"policy" json:"policy"`
+		//MaxDeviation is the maximum difference in degrees of a sensor from the others before it is excluded, 0 means not checked
+		MaxDeviation float64 `yaml:"maxDeviation" json:"max-deviation,string"`
+	} `yaml:"sensorVoting" json:"sensor-voting"`
+	Hardware    HardwareConfig    `yaml:"hardware" json:"hardware"`
+	Diagnostics DiagnosticsConfig `yaml:"diagnostics" json:"diagnostics"`
+	Cooldown    CooldownConfig    `yaml:"cooldown" json:"cooldown"`
+	Auth        AuthConfig        `yaml:"auth" json:"auth"`
+	//Simulation is used only by the dummy controller
+	Simulation SimulationConfig `yaml:"simulation" json:"simulation"`
+}
+
+// SensorConfig is the configuration of a thermocouple reader
+type SensorConfig struct {
+	Name string `yaml:"name" json:"name"`
+	//Type is the thermocouple reader chip: max31856 (default), max31855 or max6675
+	Type             string `yaml:"type" json:"type"`
+	ThermocoupleType string `yaml:"thermocoupleType" json:"thermocouple-type"`
+	BusNumber        int    `yaml:"busNumber" json:"bus-number,string"`
+	ChipNumber       int    `yaml:"chipNumber" json:"chip-number,string"`
+	AverageSamples   int    `yaml:"averageSamples" json:"average-samples,string"`
+	NoiseRejection   int    `yaml:"noiseRejection" json:"noise-rejection,string"`
+	AutoConversion   bool   `yaml:"autoConversion" json:"auto-conversion"`
+	SampleIntervalMs int    `yaml:"sampleIntervalMs" json:"sample-interval-ms,string"`
+	SampleBufferSize int    `yaml:"sampleBufferSize" json:"sample-buffer-size,string"`
+	//Hardware fault thresholds, 0 means not set
+	MaxTemperature             float64 `yaml:"maxTemperature" json:"max-temperature,string"`
+	MinTemperature             float64 `yaml:"minTemperature" json:"min-temperature,string"`
+	ColdJunctionMaxTemperature int     `yaml:"coldJunctionMaxTemperature" json:"cold-junction-max-temperature,string"`
+	ColdJunctionMinTemperature int     `yaml:"coldJunctionMinTemperature" json:"cold-junction-min-temperature,string"`
 }
 
 func (c *Config) ReadFromFile(filename string) (err error) {
@@ -45,6 +93,7 @@ func (c *Config) ReadFromFile(filename string) (err error) {
 		return err
 	}
 	defer f.Close()
+	c.setDefaults()
 
 	decoder := yaml.NewDecoder(f)
 	err = decoder.Decode(c)
@@ -61,3 +110,14 @@ func (c *Config) SaveToFile(filename string) (err error) {
 	err = encoder.Encode(c)
 	return
 }
+
+// setDefaults sets the values used when they are not in the file
+func (c *Config) setDefaults() {
+	c.Hardware = DefaultHardwareConfig()
+	c.Simulation = DefaultSimulationConfig()
+	c.Diagnostics = DefaultDiagnosticsConfig()
+	c.Cooldown = DefaultCooldownConfig()
+	c.Auth = DefaultAuthConfig()
+	c.Controller.LidOpenTimeoutSeconds = 600
+	c.Controller.EventLogFile = "events.jsonl"
+}
@@ -0,0 +1,22 @@
+package config
+
+// CooldownConfig sets the recording of the oven cooling after the end of a program
+type CooldownConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	//SampleSeconds is the time between the points recorded while cooling
+	SampleSeconds float64 `yaml:"sampleSeconds" json:"sample-seconds,string"`
+	//StopTemperature is the temperature under which the recording stops
+	StopTemperature float64 `yaml:"stopTemperature" json:"stop-temperature,string"`
+	//MaxHours is the longest recording, even if the oven is still hot
+	MaxHours float64 `yaml:"maxHours" json:"max-hours,string"`
+}
+
+// DefaultCooldownConfig records a point a minute until the oven is under 50 degrees, for at most a day
+func DefaultCooldownConfig() CooldownConfig {
+	return CooldownConfig{
+		Enabled:         true,
+		SampleSeconds:   60,
+		StopTemperature: 50,
+		MaxHours:        24,
+	}
+}
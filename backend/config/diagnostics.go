@@ -0,0 +1,25 @@
+package config
+
+// DiagnosticsConfig sets the self test done at startup or on request
+type DiagnosticsConfig struct {
+	RunAtStartup bool `yaml:"runAtStartup" json:"run-at-startup"`
+	//MaxColdJunctionDifference is the difference allowed between the thermocouple and the cold junction with the kiln cold
+	MaxColdJunctionDifference float64 `yaml:"maxColdJunctionDifference" json:"max-cold-junction-difference,string"`
+	//ColdKilnTemperature is the temperature under which the kiln is considered cold
+	ColdKilnTemperature float64 `yaml:"coldKilnTemperature" json:"cold-kiln-temperature,string"`
+	//PowerPulseSeconds is the length of the full power pulse, 0 to skip the test
+	PowerPulseSeconds float64 `yaml:"powerPulseSeconds" json:"power-pulse-seconds,string"`
+	//PowerPulseMinRise is the temperature rise expected after the pulse
+	PowerPulseMinRise float64 `yaml:"powerPulseMinRise" json:"power-pulse-min-rise,string"`
+}
+
+// DefaultDiagnosticsConfig runs the self test at startup, without the power pulse
+func DefaultDiagnosticsConfig() DiagnosticsConfig {
+	return DiagnosticsConfig{
+		RunAtStartup:              true,
+		MaxColdJunctionDifference: 15,
+		ColdKilnTemperature:       60,
+		PowerPulseSeconds:         0,
+		PowerPulseMinRise:         1,
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	PwmBackendPiBlaster = "piblaster"
+	PwmBackendSysfs     = "sysfs"
+)
+
+// HardwareConfig is the wiring of the controller: the pins are the physical pin numbers on the raspberry header
+type HardwareConfig struct {
+	//PwmBackend is piblaster (default) or sysfs
+	PwmBackend                 string `yaml:"pwmBackend" json:"pwm-backend"`
+	LedOkPin                   string `yaml:"ledOkPin" json:"led-ok-pin"`
+	LedOvenWorkingPin          string `yaml:"ledOvenWorkingPin" json:"led-oven-working-pin"`
+	OvenRelayPin               string `yaml:"ovenRelayPin" json:"oven-relay-pin"`
+	OvenRelayInverted          bool   `yaml:"ovenRelayInverted" json:"oven-relay-inverted"`
+	AirCompressorPowerPin      string `yaml:"airCompressorPowerPin" json:"air-compressor-power-pin"`
+	AirCompressorPowerInverted bool   `yaml:"airCompressorPowerInverted" json:"air-compressor-power-inverted"`
+	AirCompressorOpenPin       string `yaml:"airCompressorOpenPin" json:"air-compressor-open-pin"`
+	AirCompressorOpenInverted  bool   `yaml:"airCompressorOpenInverted" json:"air-compressor-open-inverted"`
+	SsrPowerPin                string `yaml:"ssrPowerPin" json:"ssr-power-pin"`
+	//AirActuationSeconds is how long the air compressor is powered to move the damper from open to closed
+	AirActuationSeconds float64 `yaml:"airActuationSeconds" json:"air-actuation-seconds,string"`
+	//AirOpenSwitchPin and AirClosedSwitchPin are optional limit switches confirming the damper position
+	AirOpenSwitchPin   string `yaml:"airOpenSwitchPin" json:"air-open-switch-pin"`
+	AirClosedSwitchPin string `yaml:"airClosedSwitchPin" json:"air-closed-switch-pin"`
+	AirSwitchActiveLow bool   `yaml:"airSwitchActiveLow" json:"air-switch-active-low"`
+	//EmergencyStopPin is an optional input that is active while the emergency stop button is pressed
+	EmergencyStopPin       string `yaml:"emergencyStopPin" json:"emergency-stop-pin"`
+	EmergencyStopActiveLow bool   `yaml:"emergencyStopActiveLow" json:"emergency-stop-active-low"`
+	//LidSwitchPin is an optional input that is active while the lid is open
+	LidSwitchPin       string `yaml:"lidSwitchPin" json:"lid-switch-pin"`
+	LidSwitchActiveLow bool   `yaml:"lidSwitchActiveLow" json:"lid-switch-active-low"`
+	//BuzzerPin is an optional output for an active buzzer, sounding when the program ends or on faults
+	BuzzerPin string `yaml:"buzzerPin" json:"buzzer-pin"`
+	//InputDebounceMs is how long an input must be stable before its change is accepted
+	InputDebounceMs int `yaml:"inputDebounceMs" json:"input-debounce-ms,string"`
+}
+
+// DefaultHardwareConfig returns the original wiring of the controller
+func DefaultHardwareConfig() HardwareConfig {
+	return HardwareConfig{
+		PwmBackend:                 PwmBackendPiBlaster,
+		LedOkPin:                   "16",
+		LedOvenWorkingPin:          "22",
+		OvenRelayPin:               "11",
+		OvenRelayInverted:          true,
+		AirCompressorPowerPin:      "13",
+		AirCompressorPowerInverted: true,
+		AirCompressorOpenPin:       "15",
+		AirCompressorOpenInverted:  true,
+		SsrPowerPin:                "37",
+		AirActuationSeconds:        10,
+		InputDebounceMs:            50,
+	}
+}
+
+// gpioHeaderPins are the physical pins of the 40 pin header that are GPIO (not power or ground)
+var gpioHeaderPins = map[int]bool{
+	3: true, 5: true, 7: true, 8: true, 10: true, 11: true, 12: true, 13: true, 15: true, 16: true,
+	18: true, 19: true, 21: true, 22: true, 23: true, 24: true, 26: true, 27: true, 28: true, 29: true,
+	31: true, 32: true, 33: true, 35: true, 36: true, 37: true, 38: true, 40: true,
+}
+
+// spiPins are used by the thermocouple readers on SPI0 (MOSI, MISO, SCLK, CE0, CE1)
+var spiPins = map[int]bool{19: true, 21: true, 23: true, 24: true, 26: true}
+
+// namedPins returns the pins configured, by their name. Empty pins are optional and not returned.
+func (h HardwareConfig) namedPins() [][2]string {
+	pins := [][2]string{
+		{"ledOkPin", h.LedOkPin},
+		{"ledOvenWorkingPin", h.LedOvenWorkingPin},
+		{"ovenRelayPin", h.OvenRelayPin},
+		{"airCompressorPowerPin", h.AirCompressorPowerPin},
+		{"airCompressorOpenPin", h.AirCompressorOpenPin},
+		{"ssrPowerPin", h.SsrPowerPin},
+		{"airOpenSwitchPin", h.AirOpenSwitchPin},
+		{"airClosedSwitchPin", h.AirClosedSwitchPin},
+		{"emergencyStopPin", h.EmergencyStopPin},
+		{"lidSwitchPin", h.LidSwitchPin},
+		{"buzzerPin", h.BuzzerPin},
+	}
+	res := make([][2]string, 0, len(pins))
+	for _, p := range pins {
+		if p[1] != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Validate checks that the pins are valid GPIO header pins, not used twice and not used by the SPI bus
+func (h HardwareConfig) Validate() error {
+	if h.PwmBackend != "" && h.PwmBackend != PwmBackendPiBlaster && h.PwmBackend != PwmBackendSysfs {
+		return fmt.Errorf("invalid pwm backend %s", h.PwmBackend)
+	}
+	required := map[string]string{
+		"ledOkPin": h.LedOkPin, "ledOvenWorkingPin": h.LedOvenWorkingPin, "ovenRelayPin": h.OvenRelayPin,
+		"airCompressorPowerPin": h.AirCompressorPowerPin, "airCompressorOpenPin": h.AirCompressorOpenPin, "ssrPowerPin": h.SsrPowerPin,
+	}
+	for name, pin := range required {
+		if pin == "" {
+			return fmt.Errorf("hardware: %s is required", name)
+		}
+	}
+	if h.AirActuationSeconds < 0 {
+		return fmt.Errorf("hardware: airActuationSeconds cannot be negative")
+	}
+	if h.InputDebounceMs < 0 {
+		return fmt.Errorf("hardware: inputDebounceMs cannot be negative")
+	}
+	used := make(map[int]string)
+	for _, p := range h.namedPins() {
+		name, pin := p[0], p[1]
+		number, err := strconv.Atoi(pin)
+		if err != nil {
+			return fmt.Errorf("hardware: %s %s is not a pin number", name, pin)
+		}
+		if !gpioHeaderPins[number] {
+			return fmt.Errorf("hardware: %s %d is not a GPIO pin of the header", name, number)
+		}
+		if spiPins[number] {
+			return fmt.Errorf("hardware: %s %d is used by the SPI bus", name, number)
+		}
+		if other, ok := used[number]; ok {
+			return fmt.Errorf("hardware: %s and %s use the same pin %d", other, name, number)
+		}
+		used[number] = name
+	}
+	return nil
+}
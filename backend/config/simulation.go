@@ -0,0 +1,30 @@
+package config
+
+// SimulationConfig are the parameters of the thermal simulation used by the dummy controller
+type SimulationConfig struct {
+	//TimeMultiplier makes the simulated time run faster than the real one
+	TimeMultiplier     float64 `yaml:"timeMultiplier" json:"time-multiplier,string"`
+	AmbientTemperature float64 `yaml:"ambientTemperature" json:"ambient-temperature,string"`
+	//SensorNoise is the standard deviation of the noise added to the readings, in degrees
+	SensorNoise float64 `yaml:"sensorNoise" json:"sensor-noise,string"`
+	//SensorLagSeconds is the time constant of the thermocouple following the oven temperature
+	SensorLagSeconds float64 `yaml:"sensorLagSeconds" json:"sensor-lag-seconds,string"`
+	//AirOpenLossFactor multiplies the heat lost while the air damper is open
+	AirOpenLossFactor float64 `yaml:"airOpenLossFactor" json:"air-open-loss-factor,string"`
+	//WareMass (kg) and WareHeatCapacity (J/kg K) are the load in the oven, heated with it
+	WareMass         float64 `yaml:"wareMass" json:"ware-mass,string"`
+	WareHeatCapacity float64 `yaml:"wareHeatCapacity" json:"ware-heat-capacity,string"`
+}
+
+// DefaultSimulationConfig returns an empty oven at 25 degrees, running 10 times faster than real time
+func DefaultSimulationConfig() SimulationConfig {
+	return SimulationConfig{
+		TimeMultiplier:     10,
+		AmbientTemperature: 25,
+		SensorNoise:        0.3,
+		SensorLagSeconds:   10,
+		AirOpenLossFactor:  1.3,
+		WareMass:           0,
+		WareHeatCapacity:   900,
+	}
+}
@@ -2,76 +2,140 @@ package dummyinterface
 
 import (
 	"math"
+	"sync"
 	"time"
 
 	"github.com/idalmasso/ovencontrol/backend/commoninterface"
 	"github.com/idalmasso/ovencontrol/backend/config"
 )
 
+// DummyController simulates the oven, to run the backend without the hardware. It is safe for concurrent use:
+// the simulation runs in its own goroutine, started by the first InitConfig and stopped by Terminate.
 type DummyController struct {
-	ovenTemperature, externalTemperature                                                  float64
-	insulationWidth, thermalConductivity, internalArea, thermalCapacity, weight, maxPower float64
-	actualPercentual                                                                      float64
-	timeMultiplier                                                                        float64
-	isWorking                                                                             bool
-	logger                                                                                commoninterface.Logger
+	mu               sync.Mutex
+	simulation       *thermalSimulation
+	actualPercentual float64
+	maxPower         float64
+	isWorking        bool
+	airPosition      commoninterface.AirPosition
+	indicatorState   commoninterface.IndicatorState
+	lastReading      float64
+	logger           commoninterface.Logger
+	stop, done       chan struct{}
+	faults           []*ScheduledFault
+	lastFaultID      int
 }
 
-func (d DummyController) GetTemperature() (float64, error) {
-	return math.Round(d.ovenTemperature*100) / 100, nil
+func (d *DummyController) GetTemperature() (float64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	reading, err := d.faultyReading(d.simulation.reading(), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	d.lastReading = reading
+	return math.Round(d.lastReading*100) / 100, nil
+}
+
+// GetColdJunctionTemperature simulates the electronics enclosure, that slowly follows the oven temperature
+func (d *DummyController) GetColdJunctionTemperature() (float64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	coldJunction := d.simulation.ambient + (d.simulation.temperature-d.simulation.ambient)*0.03
+	return math.Round(coldJunction*100) / 100, nil
+}
+
+// GetSensorTemperatures returns the last reading as the only sensor
+func (d *DummyController) GetSensorTemperatures() []float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return []float64{math.Round(d.lastReading*100) / 100}
+}
+
+// GetInterlocks returns the interlocks released, the simulation has no emergency stop or lid
+func (d *DummyController) GetInterlocks() commoninterface.InterlockState {
+	return commoninterface.InterlockState{}
+}
+
+// SetIndicatorState keeps the state, the simulation has no LEDs to drive
+func (d *DummyController) SetIndicatorState(state commoninterface.IndicatorState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.indicatorState = state
+}
+
+func (d *DummyController) GetIndicatorState() commoninterface.IndicatorState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.indicatorState == "" {
+		return commoninterface.IndicatorIdle
+	}
+	return d.indicatorState
+}
+
+// GetAlarms returns no alarms, the injected faults are seen only through the readings
+func (d *DummyController) GetAlarms() []commoninterface.Alarm {
+	return nil
 }
 
 func (d *DummyController) IsWorking() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	return d.isWorking
 }
 
+// InitConfig sets the oven and simulation parameters, keeping the current temperature.
+// The simulation starts at the first call.
 func (d *DummyController) InitConfig(c config.Config) {
-
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.actualPercentual = 0
-	d.externalTemperature = 25
-	for _, v := range c.Oven.InsultationWidths {
-		d.insulationWidth += v
-	}
-	d.internalArea = c.Oven.Height * c.Oven.Length * c.Oven.Width
 	d.maxPower = c.Oven.MaxPower
-	d.ovenTemperature = 25
-	d.thermalCapacity = c.Oven.ThermalCapacity
-	d.thermalConductivity = calculateConducibility(c.Oven.InsultationWidths, c.Oven.ThermalConductivities)
-	d.timeMultiplier = 10
-	d.weight = c.Oven.Weight
-
-	go func() {
-		for {
-			time.Sleep(time.Millisecond * 500)
-			ovenPower := d.actualPercentual * d.maxPower
-			lostPower := (d.thermalConductivity * d.internalArea *
-				(d.ovenTemperature - d.externalTemperature)) / d.insulationWidth
-			d.ovenTemperature += ((ovenPower - lostPower) / (d.weight * d.thermalCapacity)) * 0.5
-		}
-	}()
-}
-
-func calculateConducibility(lengths, conducibilities []float64) float64 {
-	total := 0.0
-	for _, l := range lengths {
-		total += l
+	if d.simulation == nil {
+		d.simulation = newThermalSimulation(c)
+	} else {
+		d.simulation.configure(c)
 	}
-
-	rTot := 0.0
-	for idx := range lengths {
-		rTot += lengths[idx] / conducibilities[idx]
+	if d.stop == nil {
+		d.stop, d.done = make(chan struct{}), make(chan struct{})
+		go d.run(d.stop, d.done)
 	}
+}
 
-	return total / rTot
+// run advances the simulation at each tick, by the real time elapsed multiplied by the time multiplier
+func (d *DummyController) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(simulationTick)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			d.removeExpiredFaults(now)
+			d.simulation.ambient = d.faultyAmbient(now)
+			d.simulation.advance(now.Sub(last).Seconds(), d.faultyPower(now), d.airPosition == commoninterface.AirOpen)
+			d.mu.Unlock()
+			last = now
+		}
+	}
 }
 
 func (d *DummyController) GetPercentual() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	return d.actualPercentual
 }
 func (d *DummyController) GetMaxPower() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	return d.maxPower
 }
 func (d *DummyController) SetPercentual(percent float64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.actualPercentual = percent
 	return nil
 }
@@ -84,24 +148,54 @@ func WithLogger(logger commoninterface.Logger) func(*DummyController) {
 	}
 }
 func (d *DummyController) InitStartProgram() error {
-	d.ovenTemperature = 0
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.isWorking = true
 	return nil
 }
 func (d *DummyController) EndProgram() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.isWorking = false
+	d.actualPercentual = 0
 	return nil
 }
 func (d *DummyController) OpenAir() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.airPosition = commoninterface.AirOpen
 	return nil
 }
 func (d *DummyController) CloseAir() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.airPosition = commoninterface.AirClosed
 	return nil
 }
+
+// GetAirPosition returns the last position requested, the simulated damper moves instantly
+func (d *DummyController) GetAirPosition() commoninterface.AirPosition {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.airPosition == "" {
+		return commoninterface.AirUnknown
+	}
+	return d.airPosition
+}
+
+// Terminate stops the simulation
 func (d *DummyController) Terminate() {
-	return
+	d.mu.Lock()
+	stop, done := d.stop, d.done
+	d.stop, d.done = nil, nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
 }
 func NewDummyController(options ...func(*DummyController)) *DummyController {
-	d := &DummyController{}
+	d := &DummyController{simulation: newThermalSimulation(config.Config{Simulation: config.DefaultSimulationConfig()})}
 	for _, o := range options {
 		o(d)
 	}
@@ -0,0 +1,116 @@
+package dummyinterface
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+)
+
+func TestControllerState(t *testing.T) {
+	d := NewDummyController()
+	if d.GetIndicatorState() != commoninterface.IndicatorIdle || d.GetAirPosition() != commoninterface.AirUnknown {
+		t.Errorf("new controller indicator %s and air %s, want idle and unknown", d.GetIndicatorState(), d.GetAirPosition())
+	}
+	d.SetIndicatorState(commoninterface.IndicatorHeating)
+	if state := d.GetIndicatorState(); state != commoninterface.IndicatorHeating {
+		t.Errorf("indicator = %s, want heating", state)
+	}
+	for _, tt := range []struct {
+		move func() error
+		want commoninterface.AirPosition
+	}{{d.OpenAir, commoninterface.AirOpen}, {d.CloseAir, commoninterface.AirClosed}} {
+		if err := tt.move(); err != nil || d.GetAirPosition() != tt.want {
+			t.Errorf("air = %s, %v, want %s", d.GetAirPosition(), err, tt.want)
+		}
+	}
+
+	if err := d.InitStartProgram(); err != nil || !d.IsWorking() {
+		t.Fatalf("InitStartProgram = %v, working %v", err, d.IsWorking())
+	}
+	if err := d.SetPercentual(0.7); err != nil || d.GetPercentual() != 0.7 {
+		t.Fatalf("SetPercentual = %v, percentual %v", err, d.GetPercentual())
+	}
+	if err := d.EndProgram(); err != nil || d.IsWorking() || d.GetPercentual() != 0 {
+		t.Errorf("EndProgram = %v, working %v, percentual %v, want the power off", err, d.IsWorking(), d.GetPercentual())
+	}
+}
+
+func TestControllerReadings(t *testing.T) {
+	c := testConfig()
+	//the simulation stays still while the test reads it
+	c.Simulation.TimeMultiplier = 1e-9
+	d := NewDummyController()
+	d.InitConfig(c)
+	t.Cleanup(d.Terminate)
+	d.mu.Lock()
+	d.simulation.sensorTemperature = 123.456
+	d.simulation.temperature = 123.456
+	d.mu.Unlock()
+	if temperature, err := d.GetTemperature(); err != nil || temperature != 123.46 {
+		t.Errorf("GetTemperature = %v, %v, want the reading rounded to 123.46", temperature, err)
+	}
+	if sensors := d.GetSensorTemperatures(); len(sensors) != 1 || sensors[0] != 123.46 {
+		t.Errorf("GetSensorTemperatures = %v, want the last reading", sensors)
+	}
+	if coldJunction, err := d.GetColdJunctionTemperature(); err != nil || coldJunction != 27.95 {
+		t.Errorf("GetColdJunctionTemperature = %v, %v, want 27.95", coldJunction, err)
+	}
+
+	if _, err := d.InjectFault(Fault{Type: FaultOpenCircuit}); err != nil {
+		t.Fatalf("InjectFault: %v", err)
+	}
+	if _, err := d.GetTemperature(); err == nil {
+		t.Error("GetTemperature with the open circuit did not fail")
+	}
+	if sensors := d.GetSensorTemperatures(); sensors[0] != 123.46 {
+		t.Errorf("GetSensorTemperatures after a failed read = %v, want the last good reading", sensors)
+	}
+}
+
+func TestControllerSimulation(t *testing.T) {
+	c := testConfig()
+	c.Simulation.TimeMultiplier = 100
+	d := NewDummyController()
+	d.InitConfig(c)
+	t.Cleanup(d.Terminate)
+	if err := d.SetPercentual(1); err != nil {
+		t.Fatalf("SetPercentual: %v", err)
+	}
+	//full power heats 0.1 degrees per simulated second
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		temperature, err := d.GetTemperature()
+		if err != nil {
+			t.Fatalf("GetTemperature: %v", err)
+		}
+		if temperature > 30 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("temperature = %v after 5 seconds, want the oven heating", temperature)
+		}
+		time.Sleep(simulationTick)
+	}
+
+	//a new configuration keeps the temperature and turns the power off
+	d.InitConfig(c)
+	if d.GetPercentual() != 0 {
+		t.Errorf("percentual after InitConfig = %v, want 0", d.GetPercentual())
+	}
+	if temperature, _ := d.GetTemperature(); temperature <= 30 {
+		t.Errorf("temperature after InitConfig = %v, want it kept", temperature)
+	}
+
+	d.Terminate()
+	d.Terminate()
+	d.mu.Lock()
+	stopped := d.simulation.temperature
+	d.mu.Unlock()
+	time.Sleep(3 * simulationTick)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.simulation.temperature != stopped {
+		t.Errorf("temperature changed from %v to %v after Terminate", stopped, d.simulation.temperature)
+	}
+}
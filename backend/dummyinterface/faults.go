@@ -0,0 +1,207 @@
+package dummyinterface
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// FaultType is a failure that can be injected in the simulation
+type FaultType string
+
+const (
+	//FaultOpenCircuit makes every temperature read fail as a broken thermocouple
+	FaultOpenCircuit FaultType = "open-circuit"
+	//FaultIntermittentRead makes a read fail with probability Value (0.5 if not set)
+	FaultIntermittentRead FaultType = "intermittent-read"
+	//FaultStuckSSR keeps the element at Value power (1, stuck on, if not set; 0 is stuck off), whatever the percentual requested
+	FaultStuckSSR FaultType = "stuck-ssr"
+	//FaultElementFailure reduces the element power to the fraction Value of the maximum (0 if not set)
+	FaultElementFailure FaultType = "element-failure"
+	//FaultFrozenSensor keeps returning the reading done when the fault started
+	FaultFrozenSensor FaultType = "frozen-sensor"
+	//FaultAmbientChange sets the ambient temperature to Value
+	FaultAmbientChange FaultType = "ambient-change"
+)
+
+var faultTypes = []FaultType{FaultOpenCircuit, FaultIntermittentRead, FaultStuckSSR, FaultElementFailure, FaultFrozenSensor, FaultAmbientChange}
+
+// Fault is a failure scheduled in the simulation: it starts AfterSeconds from the injection
+// and lasts DurationSeconds, until cleared if 0
+type Fault struct {
+	Type            FaultType `json:"type"`
+	AfterSeconds    float64   `json:"after-seconds"`
+	DurationSeconds float64   `json:"duration-seconds"`
+	//Value is the parameter of the fault, nil for its default: 0 is a valid value, as a SSR stuck off
+	Value *float64 `json:"value,omitempty"`
+}
+
+// defaultFaultValues are used when the fault has no value
+var defaultFaultValues = map[FaultType]float64{
+	FaultIntermittentRead: 0.5,
+	FaultStuckSSR:         1,
+	FaultElementFailure:   0,
+}
+
+// value returns the fault value, the default of the type if not set
+func (f Fault) value() float64 {
+	if f.Value != nil {
+		return *f.Value
+	}
+	return defaultFaultValues[f.Type]
+}
+
+// Validate checks the fault type and parameters
+func (f Fault) Validate() error {
+	if !slices.Contains(faultTypes, f.Type) {
+		return fmt.Errorf("unknown fault type %s", f.Type)
+	}
+	if f.AfterSeconds < 0 || f.DurationSeconds < 0 {
+		return fmt.Errorf("fault times cannot be negative")
+	}
+	if (f.Type == FaultIntermittentRead || f.Type == FaultStuckSSR || f.Type == FaultElementFailure) && (f.value() < 0 || f.value() > 1) {
+		return fmt.Errorf("fault %s value must be between 0 and 1", f.Type)
+	}
+	if f.Type == FaultAmbientChange && f.Value == nil {
+		return fmt.Errorf("fault %s needs the ambient temperature as value", f.Type)
+	}
+	return nil
+}
+
+// ScheduledFault is an injected fault with its times
+type ScheduledFault struct {
+	Fault
+	ID    int       `json:"id"`
+	Start time.Time `json:"start"`
+	//End is nil for a fault that lasts until cleared
+	End    *time.Time `json:"end,omitempty"`
+	Active bool       `json:"active"`
+	frozen *float64
+}
+
+func (f *ScheduledFault) activeAt(now time.Time) bool {
+	return !now.Before(f.Start) && (f.End == nil || now.Before(*f.End))
+}
+
+func (f *ScheduledFault) expiredAt(now time.Time) bool {
+	return f.End != nil && !now.Before(*f.End)
+}
+
+// WithFault injects a fault when the controller is created, its schedule starts from then
+func WithFault(f Fault) func(*DummyController) {
+	return func(d *DummyController) {
+		if _, err := d.InjectFault(f); err != nil && d.logger != nil {
+			d.logger.Error("Cannot inject fault", "err", err)
+		}
+	}
+}
+
+// InjectFault schedules a fault and returns its id
+func (d *DummyController) InjectFault(f Fault) (int, error) {
+	if err := f.Validate(); err != nil {
+		return 0, err
+	}
+	value := f.value()
+	f.Value = &value
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastFaultID++
+	scheduled := &ScheduledFault{Fault: f, ID: d.lastFaultID, Start: time.Now().Add(time.Duration(f.AfterSeconds * float64(time.Second)))}
+	if f.DurationSeconds > 0 {
+		end := scheduled.Start.Add(time.Duration(f.DurationSeconds * float64(time.Second)))
+		scheduled.End = &end
+	}
+	d.faults = append(d.faults, scheduled)
+	if d.logger != nil {
+		d.logger.Info("Fault injected", "type", f.Type, "id", scheduled.ID, "start", scheduled.Start, "end", scheduled.End)
+	}
+	return scheduled.ID, nil
+}
+
+// ClearFault removes a fault, returns false if it does not exist
+func (d *DummyController) ClearFault(id int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	idx := slices.IndexFunc(d.faults, func(f *ScheduledFault) bool { return f.ID == id })
+	if idx < 0 {
+		return false
+	}
+	d.faults = slices.Delete(d.faults, idx, idx+1)
+	return true
+}
+
+// ClearFaults removes all the faults
+func (d *DummyController) ClearFaults() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.faults = nil
+}
+
+// GetFaults returns the faults pending or active, the expired ones are removed
+func (d *DummyController) GetFaults() []ScheduledFault {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	d.removeExpiredFaults(now)
+	res := make([]ScheduledFault, len(d.faults))
+	for idx, f := range d.faults {
+		res[idx] = *f
+		res[idx].Active = f.activeAt(now)
+		res[idx].frozen = nil
+	}
+	return res
+}
+
+// removeExpiredFaults must be called with mu locked
+func (d *DummyController) removeExpiredFaults(now time.Time) {
+	d.faults = slices.DeleteFunc(d.faults, func(f *ScheduledFault) bool { return f.expiredAt(now) })
+}
+
+// activeFault returns the first fault of the type active now. Must be called with mu locked.
+func (d *DummyController) activeFault(t FaultType, now time.Time) *ScheduledFault {
+	for _, f := range d.faults {
+		if f.Type == t && f.activeAt(now) {
+			return f
+		}
+	}
+	return nil
+}
+
+// faultyReading applies the sensor faults to a reading. Must be called with mu locked.
+func (d *DummyController) faultyReading(reading float64, now time.Time) (float64, error) {
+	if d.activeFault(FaultOpenCircuit, now) != nil {
+		return 0, fmt.Errorf("thermocouple open circuit (injected)")
+	}
+	if f := d.activeFault(FaultIntermittentRead, now); f != nil && d.simulation.random.Float64() < f.value() {
+		return 0, fmt.Errorf("read failure (injected)")
+	}
+	if f := d.activeFault(FaultFrozenSensor, now); f != nil {
+		if f.frozen == nil {
+			frozen := d.lastReading
+			f.frozen = &frozen
+		}
+		return *f.frozen, nil
+	}
+	return reading, nil
+}
+
+// faultyPower returns the element power with the SSR and element faults. Must be called with mu locked.
+func (d *DummyController) faultyPower(now time.Time) float64 {
+	percentual := d.actualPercentual
+	if f := d.activeFault(FaultStuckSSR, now); f != nil {
+		percentual = f.value()
+	}
+	power := percentual * d.maxPower
+	if f := d.activeFault(FaultElementFailure, now); f != nil {
+		power *= f.value()
+	}
+	return power
+}
+
+// faultyAmbient returns the ambient temperature, changed by the fault if active. Must be called with mu locked.
+func (d *DummyController) faultyAmbient(now time.Time) float64 {
+	if f := d.activeFault(FaultAmbientChange, now); f != nil {
+		return f.value()
+	}
+	return d.simulation.configuredAmbient
+}
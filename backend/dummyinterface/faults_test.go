@@ -0,0 +1,180 @@
+package dummyinterface
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func value(v float64) *float64 {
+	return &v
+}
+
+func TestFaultValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fault   Fault
+		wantErr bool
+	}{
+		{name: "open circuit", fault: Fault{Type: FaultOpenCircuit}},
+		{name: "unknown type", fault: Fault{Type: "melted"}, wantErr: true},
+		{name: "negative start", fault: Fault{Type: FaultOpenCircuit, AfterSeconds: -1}, wantErr: true},
+		{name: "negative duration", fault: Fault{Type: FaultOpenCircuit, DurationSeconds: -1}, wantErr: true},
+		{name: "ssr stuck off", fault: Fault{Type: FaultStuckSSR, Value: value(0)}},
+		{name: "probability over 1", fault: Fault{Type: FaultIntermittentRead, Value: value(1.5)}, wantErr: true},
+		{name: "negative element power", fault: Fault{Type: FaultElementFailure, Value: value(-0.1)}, wantErr: true},
+		{name: "ambient without value", fault: Fault{Type: FaultAmbientChange}, wantErr: true},
+		{name: "ambient", fault: Fault{Type: FaultAmbientChange, Value: value(-5)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fault.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduledFaultTimes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	tests := []struct {
+		name        string
+		end         *time.Time
+		now         time.Time
+		wantActive  bool
+		wantExpired bool
+	}{
+		{name: "before the start", now: start.Add(-time.Second)},
+		{name: "at the start", now: start, wantActive: true},
+		{name: "until cleared", now: start.Add(24 * time.Hour), wantActive: true},
+		{name: "before the end", end: &end, now: end.Add(-time.Second), wantActive: true},
+		{name: "at the end", end: &end, now: end, wantExpired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ScheduledFault{Start: start, End: tt.end}
+			if active := f.activeAt(tt.now); active != tt.wantActive {
+				t.Errorf("activeAt = %v, want %v", active, tt.wantActive)
+			}
+			if expired := f.expiredAt(tt.now); expired != tt.wantExpired {
+				t.Errorf("expiredAt = %v, want %v", expired, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestInjectFault(t *testing.T) {
+	d := NewDummyController(WithFault(Fault{Type: FaultOpenCircuit}), WithFault(Fault{Type: "melted"}))
+	id, err := d.InjectFault(Fault{Type: FaultStuckSSR, AfterSeconds: 3600, DurationSeconds: 60})
+	if err != nil {
+		t.Fatalf("InjectFault: %v", err)
+	}
+	faults := d.GetFaults()
+	if len(faults) != 2 {
+		t.Fatalf("GetFaults = %+v, want the valid faults", faults)
+	}
+	if !faults[0].Active || faults[0].End != nil || *faults[0].Value != 0 {
+		t.Errorf("fault until cleared = %+v, want active, without end and with the default value", faults[0])
+	}
+	if faults[1].Active || faults[1].End == nil || faults[1].End.Sub(faults[1].Start) != time.Minute || *faults[1].Value != 1 {
+		t.Errorf("scheduled fault = %+v, want pending, lasting a minute, with the default value", faults[1])
+	}
+
+	encoded, err := json.Marshal(faults)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if strings.Count(string(encoded), `"end"`) != 1 {
+		t.Errorf("faults encoded as %s, want the end only for the fault with a duration", encoded)
+	}
+
+	if !d.ClearFault(id) || d.ClearFault(id) {
+		t.Error("ClearFault does not clear the fault only once")
+	}
+	past := time.Now().Add(-time.Second)
+	d.faults = append(d.faults, &ScheduledFault{Fault: Fault{Type: FaultFrozenSensor}, ID: 10, Start: past.Add(-time.Minute), End: &past})
+	if faults := d.GetFaults(); len(faults) != 1 || faults[0].Type != FaultOpenCircuit {
+		t.Errorf("GetFaults = %+v, want the expired fault removed", faults)
+	}
+	d.ClearFaults()
+	if faults := d.GetFaults(); len(faults) != 0 {
+		t.Errorf("GetFaults after ClearFaults = %+v", faults)
+	}
+}
+
+func TestFaultyReading(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		fault   Fault
+		want    float64
+		wantErr bool
+	}{
+		{name: "no fault", want: 100},
+		{name: "open circuit", fault: Fault{Type: FaultOpenCircuit}, wantErr: true},
+		{name: "read always failing", fault: Fault{Type: FaultIntermittentRead, Value: value(1)}, wantErr: true},
+		{name: "read never failing", fault: Fault{Type: FaultIntermittentRead, Value: value(0)}, want: 100},
+		{name: "frozen", fault: Fault{Type: FaultFrozenSensor}, want: 50},
+		{name: "other fault", fault: Fault{Type: FaultStuckSSR}, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDummyController()
+			d.lastReading = 50
+			if tt.fault.Type != "" {
+				d.faults = []*ScheduledFault{{Fault: tt.fault, Start: now}}
+			}
+			for range 3 {
+				got, err := d.faultyReading(100, now)
+				if (err != nil) != tt.wantErr || (err == nil && got != tt.want) {
+					t.Fatalf("faultyReading = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+				}
+				d.lastReading = got
+			}
+		})
+	}
+}
+
+func TestFaultyPower(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		faults     []Fault
+		percentual float64
+		want       float64
+	}{
+		{name: "no fault", percentual: 0.5, want: 1000},
+		{name: "ssr stuck on", faults: []Fault{{Type: FaultStuckSSR, Value: value(1)}}, want: 2000},
+		{name: "ssr stuck off", faults: []Fault{{Type: FaultStuckSSR, Value: value(0)}}, percentual: 1, want: 0},
+		{name: "element failure", faults: []Fault{{Type: FaultElementFailure, Value: value(0.25)}}, percentual: 1, want: 500},
+		{name: "both", faults: []Fault{{Type: FaultStuckSSR, Value: value(1)}, {Type: FaultElementFailure, Value: value(0.5)}}, want: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDummyController()
+			d.maxPower = 2000
+			d.actualPercentual = tt.percentual
+			for _, f := range tt.faults {
+				d.faults = append(d.faults, &ScheduledFault{Fault: f, Start: now})
+			}
+			if got := d.faultyPower(now); got != tt.want {
+				t.Errorf("faultyPower = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFaultyAmbient(t *testing.T) {
+	now := time.Now()
+	d := NewDummyController()
+	d.simulation.configuredAmbient = 25
+	if got := d.faultyAmbient(now); got != 25 {
+		t.Errorf("faultyAmbient without faults = %v, want 25", got)
+	}
+	d.faults = []*ScheduledFault{{Fault: Fault{Type: FaultAmbientChange, Value: value(-5)}, Start: now}}
+	if got := d.faultyAmbient(now); got != -5 {
+		t.Errorf("faultyAmbient with the fault = %v, want -5", got)
+	}
+}
@@ -0,0 +1,109 @@
+package dummyinterface
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/config"
+)
+
+const (
+	simulationTick = 100 * time.Millisecond
+	//maxIntegrationStep keeps the integration stable with high time multipliers
+	maxIntegrationStep = 1.0
+)
+
+// thermalSimulation is a lumped model of the oven: the element power heats the oven structure and the ware,
+// the heat is lost through the insulation to the ambient, more with the air damper open.
+// The thermocouple follows the oven temperature with a first order lag and reads with some noise.
+type thermalSimulation struct {
+	temperature, sensorTemperature float64
+	ambient, configuredAmbient     float64
+	surface, insulationWidth       float64
+	thermalConductivity            float64
+	heatCapacity                   float64
+	timeMultiplier                 float64
+	sensorNoise, sensorLag         float64
+	airOpenLossFactor              float64
+	random                         *rand.Rand
+}
+
+func newThermalSimulation(c config.Config) *thermalSimulation {
+	s := &thermalSimulation{random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	s.configure(c)
+	s.temperature = s.ambient
+	s.sensorTemperature = s.ambient
+	return s
+}
+
+// configure sets the parameters, the temperatures are kept
+func (s *thermalSimulation) configure(c config.Config) {
+	sim := c.Simulation
+	s.configuredAmbient = sim.AmbientTemperature
+	s.ambient = sim.AmbientTemperature
+	s.timeMultiplier = sim.TimeMultiplier
+	if s.timeMultiplier <= 0 {
+		s.timeMultiplier = 1
+	}
+	s.sensorNoise = sim.SensorNoise
+	s.sensorLag = sim.SensorLagSeconds
+	s.airOpenLossFactor = sim.AirOpenLossFactor
+	if s.airOpenLossFactor <= 0 {
+		s.airOpenLossFactor = 1
+	}
+	s.insulationWidth = 0
+	for _, v := range c.Oven.InsultationWidths {
+		s.insulationWidth += v
+	}
+	s.thermalConductivity = 0
+	if len(c.Oven.InsultationWidths) > 0 && len(c.Oven.InsultationWidths) == len(c.Oven.ThermalConductivities) {
+		s.thermalConductivity = calculateConducibility(c.Oven.InsultationWidths, c.Oven.ThermalConductivities)
+	}
+	//the heat is lost through the internal surface of the chamber
+	s.surface = 2 * (c.Oven.Height*c.Oven.Length + c.Oven.Height*c.Oven.Width + c.Oven.Length*c.Oven.Width)
+	s.heatCapacity = c.Oven.Weight*c.Oven.ThermalCapacity + sim.WareMass*sim.WareHeatCapacity
+}
+
+// advance moves the simulation forward by realSeconds, multiplied by the time multiplier
+func (s *thermalSimulation) advance(realSeconds, power float64, airOpen bool) {
+	remaining := realSeconds * s.timeMultiplier
+	for remaining > 0 {
+		dt := min(remaining, maxIntegrationStep)
+		remaining -= dt
+		lostPower := 0.0
+		if s.insulationWidth > 0 {
+			lostPower = s.thermalConductivity * s.surface * (s.temperature - s.ambient) / s.insulationWidth
+		}
+		if airOpen {
+			lostPower *= s.airOpenLossFactor
+		}
+		if s.heatCapacity > 0 {
+			s.temperature += (power - lostPower) / s.heatCapacity * dt
+		}
+		if s.sensorLag > 0 {
+			s.sensorTemperature += (s.temperature - s.sensorTemperature) * min(dt/s.sensorLag, 1)
+		} else {
+			s.sensorTemperature = s.temperature
+		}
+	}
+}
+
+// reading returns the thermocouple reading, with the noise
+func (s *thermalSimulation) reading() float64 {
+	return s.sensorTemperature + s.random.NormFloat64()*s.sensorNoise
+}
+
+// calculateConducibility returns the equivalent conductivity of the insulation layers
+func calculateConducibility(lengths, conducibilities []float64) float64 {
+	total := 0.0
+	for _, l := range lengths {
+		total += l
+	}
+
+	rTot := 0.0
+	for idx := range lengths {
+		rTot += lengths[idx] / conducibilities[idx]
+	}
+
+	return total / rTot
+}
@@ -0,0 +1,136 @@
+package dummyinterface
+
+import (
+	"math"
+	"testing"
+
+	"github.com/idalmasso/ovencontrol/backend/config"
+)
+
+// testConfig is a 1 m cube oven of 100 kJ/K, insulated with 10 cm at 0.1 W/m K, without sensor noise or lag
+func testConfig() config.Config {
+	c := config.Config{Simulation: config.DefaultSimulationConfig()}
+	c.Simulation.TimeMultiplier = 1
+	c.Simulation.SensorNoise = 0
+	c.Simulation.SensorLagSeconds = 0
+	c.Oven.Length, c.Oven.Height, c.Oven.Width = 1, 1, 1
+	c.Oven.InsultationWidths = []float64{0.1}
+	c.Oven.ThermalConductivities = []float64{0.1}
+	c.Oven.Weight = 100
+	c.Oven.ThermalCapacity = 1000
+	c.Oven.MaxPower = 10000
+	return c
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestSimulationHeating(t *testing.T) {
+	c := testConfig()
+	c.Oven.InsultationWidths = nil
+	c.Oven.ThermalConductivities = nil
+	tests := []struct {
+		name           string
+		timeMultiplier float64
+		realSeconds    float64
+		power          float64
+		want           float64
+	}{
+		{name: "no power", timeMultiplier: 1, realSeconds: 10, want: 25},
+		{name: "full power", timeMultiplier: 1, realSeconds: 10, power: 10000, want: 26},
+		{name: "time multiplier", timeMultiplier: 10, realSeconds: 1, power: 10000, want: 26},
+		{name: "steps shorter than a second", timeMultiplier: 1, realSeconds: 0.25, power: 10000, want: 25.025},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.Simulation.TimeMultiplier = tt.timeMultiplier
+			s := newThermalSimulation(c)
+			s.advance(tt.realSeconds, tt.power, false)
+			if !almostEqual(s.temperature, tt.want) {
+				t.Errorf("temperature = %v, want %v", s.temperature, tt.want)
+			}
+			if s.reading() != s.temperature {
+				t.Errorf("reading without lag and noise = %v, want %v", s.reading(), s.temperature)
+			}
+		})
+	}
+}
+
+func TestSimulationLosses(t *testing.T) {
+	s := newThermalSimulation(testConfig())
+	if !almostEqual(s.surface, 6) || !almostEqual(s.thermalConductivity, 0.1) {
+		t.Fatalf("surface %v, conductivity %v, want 6 and 0.1", s.surface, s.thermalConductivity)
+	}
+	//at 1025 degrees the loss is 0.1*6*1000/0.1 = 6 kW
+	s.temperature = 1025
+	s.advance(1, 6000, false)
+	if !almostEqual(s.temperature, 1025) {
+		t.Errorf("temperature with the power equal to the loss = %v, want 1025", s.temperature)
+	}
+
+	closed := newThermalSimulation(testConfig())
+	open := newThermalSimulation(testConfig())
+	closed.temperature, open.temperature = 1025, 1025
+	for range 3600 {
+		closed.advance(1, 0, false)
+		open.advance(1, 0, true)
+	}
+	if closed.temperature >= 1025 || closed.temperature <= closed.ambient {
+		t.Errorf("temperature after an hour closed = %v, want between the ambient and 1025", closed.temperature)
+	}
+	if open.temperature >= closed.temperature || open.temperature <= open.ambient {
+		t.Errorf("temperature after an hour open = %v, want between the ambient and %v", open.temperature, closed.temperature)
+	}
+}
+
+func TestSimulationSensorLag(t *testing.T) {
+	c := testConfig()
+	c.Simulation.SensorLagSeconds = 10
+	//without losses the oven stays at the step
+	c.Oven.InsultationWidths = nil
+	c.Oven.ThermalConductivities = nil
+	s := newThermalSimulation(c)
+	s.temperature = 125
+	s.advance(1, 0, false)
+	if s.sensorTemperature <= 25 || s.sensorTemperature >= s.temperature {
+		t.Errorf("sensor = %v after a second, want between 25 and %v", s.sensorTemperature, s.temperature)
+	}
+	s.advance(200, 0, false)
+	if math.Abs(s.sensorTemperature-s.temperature) > 0.01 {
+		t.Errorf("sensor = %v after 200 seconds, want %v", s.sensorTemperature, s.temperature)
+	}
+}
+
+func TestSimulationConfigure(t *testing.T) {
+	s := newThermalSimulation(testConfig())
+	s.temperature = 500
+	c := testConfig()
+	c.Simulation.TimeMultiplier = 0
+	c.Simulation.AirOpenLossFactor = 0
+	c.Simulation.AmbientTemperature = 10
+	s.configure(c)
+	if s.temperature != 500 {
+		t.Errorf("temperature after configure = %v, want 500", s.temperature)
+	}
+	if s.timeMultiplier != 1 || s.airOpenLossFactor != 1 || s.ambient != 10 {
+		t.Errorf("time multiplier %v, air loss factor %v, ambient %v, want 1, 1 and 10", s.timeMultiplier, s.airOpenLossFactor, s.ambient)
+	}
+}
+
+func TestCalculateConducibility(t *testing.T) {
+	tests := []struct {
+		lengths, conducibilities []float64
+		want                     float64
+	}{
+		{lengths: []float64{0.1}, conducibilities: []float64{0.2}, want: 0.2},
+		{lengths: []float64{0.1, 0.1}, conducibilities: []float64{0.1, 0.1}, want: 0.1},
+		{lengths: []float64{0.1, 0.1}, conducibilities: []float64{0.1, 0.3}, want: 0.15},
+		{lengths: []float64{0.2, 0.1}, conducibilities: []float64{0.4, 0.1}, want: 0.2},
+	}
+	for _, tt := range tests {
+		if got := calculateConducibility(tt.lengths, tt.conducibilities); !almostEqual(got, tt.want) {
+			t.Errorf("calculateConducibility(%v, %v) = %v, want %v", tt.lengths, tt.conducibilities, got, tt.want)
+		}
+	}
+}
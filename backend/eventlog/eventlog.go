@@ -0,0 +1,128 @@
+// Package eventlog is an append-only log of what happens to the oven, one JSON entry per line.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Severity is how important an entry is
+type Severity string
+
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Error    Severity = "error"
+	Critical Severity = "critical"
+)
+
+var severities = []Severity{Info, Warning, Error, Critical}
+
+// ParseSeverity checks a severity name
+func ParseSeverity(s string) (Severity, error) {
+	if !slices.Contains(severities, Severity(s)) {
+		return "", fmt.Errorf("unknown severity %s", s)
+	}
+	return Severity(s), nil
+}
+
+// AtLeast returns true if s is as important as other or more
+func (s Severity) AtLeast(other Severity) bool {
+	return slices.Index(severities, s) >= slices.Index(severities, other)
+}
+
+// The categories of the entries written by the oven
+const (
+	CategoryProgram = "program"
+	CategorySegment = "segment"
+	CategoryAir     = "air"
+	CategorySensor  = "sensor"
+	CategorySafety  = "safety"
+	CategoryAlarm   = "alarm"
+	CategoryConfig  = "config"
+	CategoryLid     = "lid"
+	CategoryAuth    = "auth"
+)
+
+// Entry is something that happened
+type Entry struct {
+	Time     time.Time      `json:"time"`
+	Severity Severity       `json:"severity"`
+	Category string         `json:"category"`
+	Message  string         `json:"message"`
+	Run      string         `json:"run,omitempty"`
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// Query selects the entries. Zero values select everything.
+type Query struct {
+	From, To    time.Time
+	MinSeverity Severity
+	Category    string
+	//Limit keeps the last entries selected
+	Limit int
+}
+
+// Match returns true if the entry is selected by the query
+func (q Query) Match(e Entry) bool {
+	return (q.From.IsZero() || !e.Time.Before(q.From)) &&
+		(q.To.IsZero() || !e.Time.After(q.To)) &&
+		(q.MinSeverity == "" || e.Severity.AtLeast(q.MinSeverity)) &&
+		(q.Category == "" || e.Category == q.Category)
+}
+
+// writeMu serializes the writes, the same file is written by the program and by the server requests
+var writeMu sync.Mutex
+
+// Append adds the entries at the end of the log file, creating it if needed
+func Append(path string, entries ...Entry) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read returns the entries of the log file selected by the query, in order. A missing file is an empty log.
+// Lines that cannot be read, as one cut by a power loss, are skipped.
+func Read(path string, q Query) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if q.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,112 @@
+// Package events distributes what happens in the oven (data points, state changes, alarms) to the streaming clients.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DataPoint = "data-point"
+	State     = "state"
+	Alarm     = "alarm"
+	//Reset is sent to a client resuming from an event no longer kept: it must reload the full state
+	Reset = "reset"
+
+	defaultKept      = 2000
+	subscriberBuffer = 256
+)
+
+// Event is something happened, numbered with a sequence increasing from 1
+type Event struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	Data any       `json:"data"`
+}
+
+// Publisher is where the events are sent
+type Publisher interface {
+	Publish(eventType string, data any)
+}
+
+// Hub keeps the last events and sends the new ones to the subscribers
+type Hub struct {
+	mu          sync.Mutex
+	seq         uint64
+	kept        []Event
+	maxKept     int
+	subscribers map[chan Event]struct{}
+}
+
+// NewHub returns a hub keeping the last maxKept events to resume the clients, a default if 0
+func NewHub(maxKept int) *Hub {
+	if maxKept <= 0 {
+		maxKept = defaultKept
+	}
+	return &Hub{maxKept: maxKept, subscribers: make(map[chan Event]struct{})}
+}
+
+// Publish numbers the event and sends it to the subscribers. A subscriber too slow to receive it is dropped,
+// its channel is closed and it can resume from the last event received.
+func (h *Hub) Publish(eventType string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	e := Event{Seq: h.seq, Time: time.Now(), Type: eventType, Data: data}
+	h.kept = append(h.kept, e)
+	if len(h.kept) > h.maxKept {
+		h.kept = h.kept[len(h.kept)-h.maxKept:]
+	}
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the events after lastSeq still kept and the channel of the new ones.
+// If events after lastSeq have been lost the backlog is only a Reset event.
+// Use lastSeq 0 to receive only the new events.
+func (h *Hub) Subscribe(lastSeq uint64) ([]Event, chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var backlog []Event
+	switch {
+	case lastSeq > h.seq:
+		//the server restarted, the client numbering is not valid anymore
+		backlog = append(backlog, Event{Seq: h.seq, Time: time.Now(), Type: Reset})
+	case lastSeq == 0 || lastSeq == h.seq:
+	case len(h.kept) == 0 || h.kept[0].Seq > lastSeq+1:
+		backlog = append(backlog, Event{Seq: h.seq, Time: time.Now(), Type: Reset})
+	default:
+		for _, e := range h.kept {
+			if e.Seq > lastSeq {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+	ch := make(chan Event, subscriberBuffer)
+	h.subscribers[ch] = struct{}{}
+	return backlog, ch
+}
+
+// Unsubscribe stops sending events to the channel
+func (h *Hub) Unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// LastSeq returns the number of the last event published
+func (h *Hub) LastSeq() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
@@ -0,0 +1,23 @@
+package hwinterface
+
+import (
+	"gobot.io/x/gobot/v2"
+	"gobot.io/x/gobot/v2/drivers/gpio"
+	"gobot.io/x/gobot/v2/drivers/spi"
+)
+
+// Adaptor is the hardware used by the controller: the raspberry adaptor, or hwfake.Adaptor to run without it
+type Adaptor interface {
+	gobot.Connection
+	gpio.DigitalReader
+	gpio.DigitalWriter
+	gpio.PwmWriter
+	spi.Connector
+}
+
+// WithAdaptor sets the adaptor used instead of the raspberry one, that is created when not set
+func WithAdaptor(a Adaptor) func(*piController) {
+	return func(pc *piController) {
+		pc.adaptor = a
+	}
+}
@@ -0,0 +1,204 @@
+package hwinterface
+
+import (
+	"sync"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"gobot.io/x/gobot/v2/drivers/gpio"
+)
+
+const (
+	defaultAirActuationTime = 10 * time.Second
+	limitSwitchPollTime     = 100 * time.Millisecond
+)
+
+// airActuator moves the air damper: the direction relay selects open or close, the power relay moves the compressor
+// for the actuation time. Requests are serialized: a new request stops the movement in progress.
+// If the limit switches are configured they are used to stop the movement and to confirm the position.
+type airActuator struct {
+	mu                          sync.Mutex
+	stateMu                     sync.RWMutex
+	power, direction            *gpio.RelayDriver
+	reader                      gpio.DigitalReader
+	openSwitchPin, closedSwitch string
+	switchActiveLow             bool
+	actuationTime               time.Duration
+	position                    commoninterface.AirPosition
+	stop, done                  chan struct{}
+	logger                      commoninterface.Logger
+	onError                     func(message string)
+}
+
+func newAirActuator(power, direction *gpio.RelayDriver, reader gpio.DigitalReader, actuationTime time.Duration, logger commoninterface.Logger) *airActuator {
+	if actuationTime <= 0 {
+		actuationTime = defaultAirActuationTime
+	}
+	return &airActuator{
+		power:         power,
+		direction:     direction,
+		reader:        reader,
+		actuationTime: actuationTime,
+		position:      commoninterface.AirUnknown,
+		logger:        logger,
+	}
+}
+
+// withLimitSwitches sets the input pins that are active when the damper is fully open or closed
+func (a *airActuator) withLimitSwitches(openPin, closedPin string, activeLow bool) *airActuator {
+	a.openSwitchPin = openPin
+	a.closedSwitch = closedPin
+	a.switchActiveLow = activeLow
+	return a
+}
+
+func (a *airActuator) Open() error {
+	return a.move(commoninterface.AirOpen)
+}
+
+func (a *airActuator) Close() error {
+	return a.move(commoninterface.AirClosed)
+}
+
+// Position returns the damper position. When not moving, the limit switches (if any) have the last word.
+func (a *airActuator) Position() commoninterface.AirPosition {
+	a.stateMu.RLock()
+	position := a.position
+	a.stateMu.RUnlock()
+	if position == commoninterface.AirMoving {
+		return position
+	}
+	if switchPosition, ok := a.switchPosition(); ok {
+		return switchPosition
+	}
+	return position
+}
+
+// Halt stops any movement in progress
+func (a *airActuator) Halt() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopMovement()
+	a.power.Off()
+}
+
+func (a *airActuator) move(target commoninterface.AirPosition) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopMovement()
+
+	//first set the direction, then give power
+	var err error
+	if target == commoninterface.AirOpen {
+		err = a.direction.On()
+	} else {
+		err = a.direction.Off()
+	}
+	if err != nil {
+		a.setPosition(commoninterface.AirUnknown)
+		return err
+	}
+	if err := a.power.On(); err != nil {
+		a.setPosition(commoninterface.AirUnknown)
+		return err
+	}
+	a.setPosition(commoninterface.AirMoving)
+	a.stop, a.done = make(chan struct{}), make(chan struct{})
+	go a.waitMovement(target, a.stop, a.done)
+	return nil
+}
+
+// stopMovement stops the movement goroutine and waits for it. Must be called with mu locked.
+func (a *airActuator) stopMovement() {
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	<-a.done
+	a.stop, a.done = nil, nil
+}
+
+func (a *airActuator) waitMovement(target commoninterface.AirPosition, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	timer := time.NewTimer(a.actuationTime)
+	defer timer.Stop()
+	ticker := time.NewTicker(limitSwitchPollTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			// interrupted by another request, that will set the new position
+			a.power.Off()
+			a.setPosition(commoninterface.AirUnknown)
+			return
+		case <-ticker.C:
+			if position, ok := a.switchPosition(); ok && position == target {
+				a.power.Off()
+				a.setPosition(target)
+				return
+			}
+		case <-timer.C:
+			a.power.Off()
+			if position, ok := a.switchPosition(); ok && position != target {
+				a.setPosition(commoninterface.AirUnknown)
+				a.error("air damper did not reach the " + string(target) + " position")
+				return
+			} else if !ok && a.hasLimitSwitches() {
+				a.setPosition(commoninterface.AirUnknown)
+				a.error("air damper limit switches cannot be read")
+				return
+			}
+			a.setPosition(target)
+			return
+		}
+	}
+}
+
+func (a *airActuator) setPosition(position commoninterface.AirPosition) {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	a.position = position
+}
+
+func (a *airActuator) error(message string) {
+	if a.onError != nil {
+		a.onError(message)
+	} else if a.logger != nil {
+		a.logger.Error(message)
+	}
+}
+
+func (a *airActuator) hasLimitSwitches() bool {
+	return a.openSwitchPin != "" || a.closedSwitch != ""
+}
+
+// switchPosition returns the position read from the limit switches, false if there are no switches
+// or the reading does not tell the position
+func (a *airActuator) switchPosition() (commoninterface.AirPosition, bool) {
+	if !a.hasLimitSwitches() || a.reader == nil {
+		return commoninterface.AirUnknown, false
+	}
+	open, openOk := a.readSwitch(a.openSwitchPin)
+	closed, closedOk := a.readSwitch(a.closedSwitch)
+	switch {
+	case openOk && closedOk && open && closed:
+		return commoninterface.AirUnknown, false
+	case openOk && open:
+		return commoninterface.AirOpen, true
+	case closedOk && closed:
+		return commoninterface.AirClosed, true
+	default:
+		return commoninterface.AirUnknown, false
+	}
+}
+
+func (a *airActuator) readSwitch(pin string) (bool, bool) {
+	if pin == "" {
+		return false, false
+	}
+	value, err := a.reader.DigitalRead(pin)
+	if err != nil {
+		return false, false
+	}
+	return (value == 1) != a.switchActiveLow, true
+}
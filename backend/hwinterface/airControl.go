@@ -1,33 +1,16 @@
 package hwinterface
 
-import "time"
+import "github.com/idalmasso/ovencontrol/backend/commoninterface"
 
 func (d *piController) OpenAir() error {
-	//first set the open relay as 1
-	if err := d.airCompressorOpen.On(); err != nil {
-		return err
-	}
-	if err := d.airCompressorPower.On(); err != nil {
-		return err
-	}
-	go func() {
-		time.Sleep(10 * time.Second)
-		d.airCompressorPower.Off()
-	}()
-	return nil
+	return d.air.Open()
 }
 
 func (d *piController) CloseAir() error {
-	//first set the open relay as 0
-	if err := d.airCompressorOpen.Off(); err != nil {
-		return err
-	}
-	if err := d.airCompressorPower.On(); err != nil {
-		return err
-	}
-	go func() {
-		time.Sleep(10 * time.Second)
-		d.airCompressorPower.Off()
-	}()
-	return nil
+	return d.air.Close()
+}
+
+// GetAirPosition returns the position of the air damper
+func (d *piController) GetAirPosition() commoninterface.AirPosition {
+	return d.air.Position()
 }
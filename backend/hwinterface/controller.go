@@ -1,22 +1,29 @@
 package hwinterface
 
 import (
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/idalmasso/ovencontrol/backend/commoninterface"
 	"github.com/idalmasso/ovencontrol/backend/config"
 	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers"
-	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi"
 	"gobot.io/x/gobot/v2/drivers/gpio"
 	"gobot.io/x/gobot/v2/platforms/adaptors"
 	"gobot.io/x/gobot/v2/platforms/raspi"
 )
 
+const (
+	defaultSampleInterval   = 250 * time.Millisecond
+	defaultSampleBufferSize = 64
+)
+
 type piController struct {
 	ledOvenWorking, ledOk                                 *gpio.LedDriver
 	ssrPowerController                                    *drivers.SSRRegulatorDriver
-	temperatureReader                                     *spi.MAX31856Driver
+	sensors                                               []*sensorChannel
 	ovenRelayPower, airCompressorPower, airCompressorOpen *gpio.RelayDriver
 	gpio.RelayDriver
-	buttonPressFunc     func()
 	actualPercentual    float64
 	maxPower            float64
 	internalArea        float64
@@ -25,6 +32,25 @@ type piController struct {
 	weight              float64
 	insulationWidth     float64
 	logger              commoninterface.Logger
+	safetyMu            sync.Mutex
+	tripped             bool
+	tripReason          string
+	configuration       config.Config
+	sensorsMu           sync.Mutex
+	votingPolicy        string
+	maxDeviation        float64
+	sensorsDisagree     bool
+	alarmsMu            sync.Mutex
+	alarms              []commoninterface.Alarm
+	alarmHandler        func(commoninterface.Alarm)
+	air                 *airActuator
+	interlocksMu        sync.RWMutex
+	interlocks          commoninterface.InterlockState
+	interlocksStop      chan struct{}
+	interlocksDone      chan struct{}
+	indicator           *indicator
+	buzzer              *gpio.BuzzerDriver
+	adaptor             Adaptor
 }
 
 func (d *piController) InitConfig(c config.Config) {
@@ -38,11 +64,57 @@ func (d *piController) InitConfig(c config.Config) {
 	d.thermalCapacity = c.Oven.ThermalCapacity
 	d.thermalConductivity = calculateConducibility(c.Oven.InsultationWidths, c.Oven.ThermalConductivities)
 	d.weight = c.Oven.Weight
+	d.votingPolicy = c.SensorVoting.Policy
+	d.maxDeviation = c.SensorVoting.MaxDeviation
+	if len(c.Sensors) > 0 && len(c.Sensors) != len(d.sensors) && d.logger != nil {
+		d.logger.Error("The number of sensors changed, restart needed to use the new sensors")
+	}
+	for idx, channel := range d.sensors {
+		sensorConfig := channel.config
+		if idx < len(c.Sensors) {
+			sensorConfig = c.Sensors[idx]
+		}
+		d.configureSampler(channel, sensorConfig)
+		d.configureThresholds(channel, sensorConfig)
+	}
+}
+
+// configureSampler starts or stops the background sampling of the thermocouple reader
+func (d *piController) configureSampler(channel *sensorChannel, c config.SensorConfig) {
+	sampler, ok := channel.sensor.(samplingSensor)
+	if !ok {
+		if c.AutoConversion && d.logger != nil {
+			d.logger.Error("Auto conversion not supported by the sensor", "sensor", channel.name, "type", c.Type)
+		}
+		return
+	}
+	if !c.AutoConversion {
+		if err := sampler.StopAutoConversion(); err != nil && d.logger != nil {
+			d.logger.Error("Cannot stop auto conversion", "err", err)
+		}
+		return
+	}
+	interval := time.Duration(c.SampleIntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultSampleInterval
+	}
+	bufferSize := c.SampleBufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultSampleBufferSize
+	}
+	if err := sampler.StartAutoConversion(interval, bufferSize); err != nil && d.logger != nil {
+		d.logger.Error("Cannot start auto conversion", "err", err)
+	}
 }
 
 func (d *piController) SetLogger(logger commoninterface.Logger) {
 	d.logger = logger
-	d.temperatureReader.SetLogger(logger)
+	for _, s := range d.sensors {
+		s.sensor.SetLogger(logger)
+	}
+	if d.air != nil {
+		d.air.logger = logger
+	}
 }
 
 func WithLogger(logger commoninterface.Logger) func(*piController) {
@@ -51,6 +123,13 @@ func WithLogger(logger commoninterface.Logger) func(*piController) {
 	}
 }
 
+// WithConfig sets the configuration used to create the hardware drivers (sensors type, chip...)
+func WithConfig(c config.Config) func(*piController) {
+	return func(pc *piController) {
+		pc.configuration = c
+	}
+}
+
 func calculateConducibility(lengths, conducibilities []float64) float64 {
 	total := 0.0
 	for _, l := range lengths {
@@ -65,33 +144,69 @@ func calculateConducibility(lengths, conducibilities []float64) float64 {
 	return total / rTot
 }
 
-func NewController(options ...func(*piController)) *piController {
-	r := raspi.NewAdaptor(adaptors.WithPWMUsePiBlaster())
+func relayOptions(inverted bool) []interface{} {
+	if inverted {
+		return []interface{}{gpio.WithRelayInverted()}
+	}
+	return nil
+}
+
+func NewController(options ...func(*piController)) (*piController, error) {
+	pi := &piController{}
+	for _, o := range options {
+		o(pi)
+	}
+	hardware := pi.configuration.Hardware
+	if hardware == (config.HardwareConfig{}) {
+		hardware = config.DefaultHardwareConfig()
+	}
+	if err := hardware.Validate(); err != nil {
+		return nil, err
+	}
+	r := pi.adaptor
+	if r == nil {
+		if hardware.PwmBackend == config.PwmBackendSysfs {
+			r = raspi.NewAdaptor()
+		} else {
+			r = raspi.NewAdaptor(adaptors.WithPWMUsePiBlaster())
+		}
+		pi.adaptor = r
+	}
 	r.Connect()
 
 	//This is showing the server is on, I don't need to pass to the piController
-	ledOk := gpio.NewLedDriver(r, "16")
+	ledOk := gpio.NewLedDriver(r, hardware.LedOkPin)
 	ledOk.Start()
 	ledOk.On()
-	ovenRelayPower := gpio.NewRelayDriver(r, "11", gpio.WithRelayInverted())
-	airCompressorPower := gpio.NewRelayDriver(r, "13", gpio.WithRelayInverted())
-	airCompressorOpen := gpio.NewRelayDriver(r, "15", gpio.WithRelayInverted())
-	ledOvenWorking := gpio.NewLedDriver(r, "22")
-	ssrPowerController := drivers.NewSSRRegulator(r, "37")
-
-	temperatureReader := spi.NewMAX31856Driver(r, spi.WithAverageSample(4), spi.WithNoiseRejection(50), spi.WithThermocoupleType(spi.N))
-
-	pi := &piController{temperatureReader: temperatureReader,
-		ssrPowerController: ssrPowerController,
-		ledOvenWorking:     ledOvenWorking,
-		ovenRelayPower:     ovenRelayPower,
-		airCompressorPower: airCompressorPower,
-		airCompressorOpen:  airCompressorOpen,
-		ledOk:              ledOk}
-	for _, o := range options {
-		o(pi)
+	ovenRelayPower := gpio.NewRelayDriver(r, hardware.OvenRelayPin, relayOptions(hardware.OvenRelayInverted)...)
+	airCompressorPower := gpio.NewRelayDriver(r, hardware.AirCompressorPowerPin, relayOptions(hardware.AirCompressorPowerInverted)...)
+	airCompressorOpen := gpio.NewRelayDriver(r, hardware.AirCompressorOpenPin, relayOptions(hardware.AirCompressorOpenInverted)...)
+	ledOvenWorking := gpio.NewLedDriver(r, hardware.LedOvenWorkingPin)
+	ssrPowerController := drivers.NewSSRRegulator(r, hardware.SsrPowerPin)
+
+	sensors, err := newSensorChannels(r, pi.configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	pi.sensors = sensors
+	pi.ssrPowerController = ssrPowerController
+	pi.ledOvenWorking = ledOvenWorking
+	pi.ovenRelayPower = ovenRelayPower
+	pi.airCompressorPower = airCompressorPower
+	pi.airCompressorOpen = airCompressorOpen
+	pi.ledOk = ledOk
+	pi.air = newAirActuator(airCompressorPower, airCompressorOpen, r, time.Duration(hardware.AirActuationSeconds*float64(time.Second)), pi.logger).
+		withLimitSwitches(hardware.AirOpenSwitchPin, hardware.AirClosedSwitchPin, hardware.AirSwitchActiveLow)
+	pi.air.onError = func(message string) { pi.raiseAlarm("air", message) }
+	for _, s := range sensors {
+		if pi.logger != nil {
+			s.sensor.SetLogger(pi.logger)
+		}
+		if err := s.sensor.Start(); err != nil {
+			return nil, fmt.Errorf("sensor %s: %w", s.name, err)
+		}
 	}
-	temperatureReader.Start()
 	ledOvenWorking.Start()
 	ssrPowerController.Start()
 	ovenRelayPower.Start()
@@ -102,24 +217,38 @@ func NewController(options ...func(*piController)) *piController {
 	airCompressorPower.Off()
 	airCompressorOpen.On()
 	pi.ssrPowerController.SetPower(0)
-	return pi
+	var buzzer onOffOutput
+	if hardware.BuzzerPin != "" {
+		pi.buzzer = gpio.NewBuzzerDriver(r, hardware.BuzzerPin)
+		pi.buzzer.Start()
+		buzzer = pi.buzzer
+	}
+	pi.indicator = newIndicator(ledOk, ledOvenWorking, buzzer)
+	pi.indicator.Start()
+	pi.startInterlocks(r, hardware)
+	return pi, nil
 }
 
 func (d *piController) Terminate() {
 	if d.logger != nil {
 		d.logger.Info("Terminate called, shutting down all pins")
 	}
-	d.ledOk.Off()
+	d.stopInterlocks()
+	d.indicator.Halt()
 	d.ledOk.Halt()
-	d.temperatureReader.Halt()
+	for _, s := range d.sensors {
+		s.sensor.Halt()
+	}
 	d.ssrPowerController.SetPower(0)
 	d.ssrPowerController.Halt()
 	d.ovenRelayPower.Off()
 	d.ovenRelayPower.Halt()
-	d.airCompressorPower.Off()
+	d.air.Halt()
 	d.airCompressorPower.Halt()
 	d.airCompressorOpen.Off()
 	d.airCompressorOpen.Halt()
-	d.ledOvenWorking.Off()
 	d.ledOvenWorking.Halt()
+	if d.buzzer != nil {
+		d.buzzer.Halt()
+	}
 }
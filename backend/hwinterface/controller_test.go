@@ -0,0 +1,199 @@
+package hwinterface_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/hwinterface"
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/hwfake"
+)
+
+// testConfig is the default wiring with the given sensors
+func testConfig(sensors ...config.SensorConfig) config.Config {
+	c := config.Config{Sensors: sensors}
+	c.Hardware = config.DefaultHardwareConfig()
+	c.Oven.MaxPower = 3000
+	return c
+}
+
+// controller is the part of the controller used by the tests
+type controller interface {
+	InitConfig(c config.Config)
+	GetTemperature() (float64, error)
+	GetColdJunctionTemperature() (float64, error)
+	SetPercentual(f float64) error
+	GetPercentual() float64
+	InitStartProgram() error
+	EndProgram() error
+	GetIndicatorState() commoninterface.IndicatorState
+	GetAlarms() []commoninterface.Alarm
+	Terminate()
+}
+
+func newTestController(t *testing.T, c config.Config, a *hwfake.Adaptor) controller {
+	t.Helper()
+	pi, err := hwinterface.NewController(hwinterface.WithAdaptor(a), hwinterface.WithConfig(c),
+		hwinterface.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatalf("NewController: %v", err)
+	}
+	pi.InitConfig(c)
+	t.Cleanup(pi.Terminate)
+	return pi
+}
+
+func TestControllerReadsTheSensor(t *testing.T) {
+	a := hwfake.NewAdaptor()
+	chip := a.AttachMAX31856(0, 0)
+	chip.SetTemperature(512.5)
+	chip.SetColdJunctionTemperature(31.25)
+	pi := newTestController(t, testConfig(), a)
+
+	temperature, err := pi.GetTemperature()
+	if err != nil || temperature != 512.5 {
+		t.Errorf("GetTemperature = %v, %v, want 512.5", temperature, err)
+	}
+	coldJunction, err := pi.GetColdJunctionTemperature()
+	if err != nil || coldJunction != 31.25 {
+		t.Errorf("GetColdJunctionTemperature = %v, %v, want 31.25", coldJunction, err)
+	}
+}
+
+func TestControllerPower(t *testing.T) {
+	tests := []struct {
+		percentual float64
+		want       byte
+	}{
+		{0, 0},
+		{0.5, 127},
+		{1, 255},
+	}
+	a := hwfake.NewAdaptor()
+	a.AttachMAX31856(0, 0)
+	c := testConfig()
+	pi := newTestController(t, c, a)
+	ssrPin := c.Hardware.SsrPowerPin
+	if writes := a.PinWrites(ssrPin); len(writes) == 0 || !writes[len(writes)-1].Pwm || writes[len(writes)-1].Value != 0 {
+		t.Fatalf("SSR not set to 0 at start: %v", writes)
+	}
+	for _, tt := range tests {
+		a.Reset()
+		if err := pi.SetPercentual(tt.percentual); err != nil {
+			t.Fatalf("SetPercentual(%v): %v", tt.percentual, err)
+		}
+		writes := a.PinWrites(ssrPin)
+		if len(writes) != 1 || !writes[0].Pwm || writes[0].Value != tt.want {
+			t.Errorf("SetPercentual(%v) SSR writes = %+v, want one PWM write of %d", tt.percentual, writes, tt.want)
+		}
+		if got := pi.GetPercentual(); got != tt.percentual {
+			t.Errorf("GetPercentual = %v, want %v", got, tt.percentual)
+		}
+	}
+}
+
+func TestControllerOvenRelay(t *testing.T) {
+	a := hwfake.NewAdaptor()
+	a.AttachMAX31856(0, 0)
+	c := testConfig()
+	pi := newTestController(t, c, a)
+	relayPin := c.Hardware.OvenRelayPin
+	//the default relay is inverted: on is low
+	if got := a.Pin(relayPin); got != 1 {
+		t.Errorf("oven relay pin = %d at start, want 1 (off)", got)
+	}
+	if err := pi.InitStartProgram(); err != nil {
+		t.Fatalf("InitStartProgram: %v", err)
+	}
+	if got := a.Pin(relayPin); got != 0 {
+		t.Errorf("oven relay pin = %d while working, want 0 (on)", got)
+	}
+	if state := pi.GetIndicatorState(); state != commoninterface.IndicatorHeating {
+		t.Errorf("indicator = %s while working, want %s", state, commoninterface.IndicatorHeating)
+	}
+	if err := pi.EndProgram(); err != nil {
+		t.Fatalf("EndProgram: %v", err)
+	}
+	if got := a.Pin(relayPin); got != 1 {
+		t.Errorf("oven relay pin = %d after the program, want 1 (off)", got)
+	}
+}
+
+func TestControllerThresholdTrip(t *testing.T) {
+	a := hwfake.NewAdaptor()
+	chip := a.AttachMAX31856(0, 0)
+	c := testConfig(config.SensorConfig{Name: "kiln", MaxTemperature: 1300})
+	pi := newTestController(t, c, a)
+	if err := pi.InitStartProgram(); err != nil {
+		t.Fatalf("InitStartProgram: %v", err)
+	}
+	if err := pi.SetPercentual(1); err != nil {
+		t.Fatalf("SetPercentual: %v", err)
+	}
+
+	chip.SetTemperature(1310)
+	if _, err := pi.GetTemperature(); !errors.Is(err, commoninterface.ErrSafetyTrip) {
+		t.Fatalf("GetTemperature over the threshold error = %v, want %v", err, commoninterface.ErrSafetyTrip)
+	}
+	if got := a.Pin(c.Hardware.SsrPowerPin); got != 0 {
+		t.Errorf("SSR = %d after the trip, want 0", got)
+	}
+	if err := pi.SetPercentual(1); !errors.Is(err, commoninterface.ErrSafetyTrip) {
+		t.Errorf("SetPercentual after the trip error = %v, want %v", err, commoninterface.ErrSafetyTrip)
+	}
+	if state := pi.GetIndicatorState(); state != commoninterface.IndicatorFault {
+		t.Errorf("indicator = %s after the trip, want %s", state, commoninterface.IndicatorFault)
+	}
+
+	//the trip is cleared by a new program only once the fault is gone
+	if err := pi.InitStartProgram(); err == nil {
+		t.Error("InitStartProgram with the fault still present should fail")
+	}
+	chip.SetTemperature(1000)
+	pi.GetTemperature()
+	if err := pi.InitStartProgram(); err != nil {
+		t.Errorf("InitStartProgram with the fault gone: %v", err)
+	}
+}
+
+func TestControllerRedundantSensors(t *testing.T) {
+	a := hwfake.NewAdaptor()
+	first, second := a.AttachMAX31856(0, 0), a.AttachMAX31856(0, 1)
+	c := testConfig(config.SensorConfig{Name: "top", ChipNumber: 0}, config.SensorConfig{Name: "bottom", ChipNumber: 1})
+	c.SensorVoting.Policy = hwinterface.VotingMax
+	c.SensorVoting.MaxDeviation = 20
+	pi := newTestController(t, c, a)
+
+	first.SetTemperature(800)
+	second.SetTemperature(810)
+	if got, err := pi.GetTemperature(); err != nil || got != 810 {
+		t.Errorf("GetTemperature = %v, %v, want 810", got, err)
+	}
+	if alarms := pi.GetAlarms(); len(alarms) != 0 {
+		t.Errorf("alarms with the sensors agreeing: %v", alarms)
+	}
+
+	second.SetOpen(true)
+	if got, err := pi.GetTemperature(); err != nil || got != 800 {
+		t.Errorf("GetTemperature with a sensor open = %v, %v, want 800", got, err)
+	}
+	if alarms := pi.GetAlarms(); len(alarms) != 1 || alarms[0].Source != "bottom" {
+		t.Errorf("alarms with the bottom sensor open = %v", alarms)
+	}
+}
+
+func TestControllerColdJunctionNotSupported(t *testing.T) {
+	a := hwfake.NewAdaptor()
+	a.Connection(0, 0).SetCommandResponse(0x03, 0x20)
+	pi := newTestController(t, testConfig(config.SensorConfig{Name: "kiln", Type: "max6675"}), a)
+
+	if got, err := pi.GetTemperature(); err != nil || got != 25 {
+		t.Errorf("GetTemperature = %v, %v, want 25", got, err)
+	}
+	if _, err := pi.GetColdJunctionTemperature(); !errors.Is(err, commoninterface.ErrNotSupported) {
+		t.Errorf("GetColdJunctionTemperature error = %v, want %v", err, commoninterface.ErrNotSupported)
+	}
+}
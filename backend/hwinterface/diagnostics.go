@@ -0,0 +1,49 @@
+package hwinterface
+
+import (
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"gobot.io/x/gobot/v2/drivers/gpio"
+)
+
+const airPowerTestPulse = 100 * time.Millisecond
+
+// TestOutputs toggles the relays and checks they follow. The SSR stays at 0, so the oven is not heated.
+// The air compressor is powered only for a short pulse in the direction already selected, not to move the damper.
+func (d *piController) TestOutputs() []commoninterface.DiagnosticCheck {
+	if d.GetInterlocks().EmergencyStop {
+		return []commoninterface.DiagnosticCheck{{Name: "outputs", Status: commoninterface.DiagnosticFail, Message: "emergency stop pressed"}}
+	}
+	checks := []commoninterface.DiagnosticCheck{}
+	if err := d.ssrPowerController.SetPower(0); err != nil {
+		checks = append(checks, commoninterface.DiagnosticCheck{Name: "ssr", Status: commoninterface.DiagnosticFail, Message: err.Error()})
+	} else {
+		checks = append(checks, commoninterface.DiagnosticCheck{Name: "ssr", Status: commoninterface.DiagnosticPass})
+	}
+	checks = append(checks, toggleRelay("oven relay", d.ovenRelayPower, 0))
+	checks = append(checks, toggleRelay("air direction relay", d.airCompressorOpen, 0))
+	if d.air.Position() == commoninterface.AirMoving {
+		checks = append(checks, commoninterface.DiagnosticCheck{Name: "air power relay", Status: commoninterface.DiagnosticSkipped, Message: "air damper moving"})
+	} else {
+		checks = append(checks, toggleRelay("air power relay", d.airCompressorPower, airPowerTestPulse))
+	}
+	return checks
+}
+
+// toggleRelay inverts the relay for the time given, checks the state and restores it
+func toggleRelay(name string, relay *gpio.RelayDriver, hold time.Duration) commoninterface.DiagnosticCheck {
+	initial := relay.State()
+	if err := relay.Toggle(); err != nil {
+		return commoninterface.DiagnosticCheck{Name: name, Status: commoninterface.DiagnosticFail, Message: err.Error()}
+	}
+	toggled := relay.State()
+	time.Sleep(hold)
+	if err := relay.Toggle(); err != nil {
+		return commoninterface.DiagnosticCheck{Name: name, Status: commoninterface.DiagnosticFail, Message: "cannot restore: " + err.Error()}
+	}
+	if toggled == initial || relay.State() != initial {
+		return commoninterface.DiagnosticCheck{Name: name, Status: commoninterface.DiagnosticFail, Message: "state not changed"}
+	}
+	return commoninterface.DiagnosticCheck{Name: name, Status: commoninterface.DiagnosticPass}
+}
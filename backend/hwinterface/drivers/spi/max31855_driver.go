@@ -0,0 +1,94 @@
+package spi
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"gobot.io/x/gobot/v2/drivers/spi"
+)
+
+const (
+	MAX31855_FAULT     uint32 = 0x10000
+	MAX31855_FAULT_SCV uint32 = 0x04
+	MAX31855_FAULT_SCG uint32 = 0x02
+	MAX31855_FAULT_OC  uint32 = 0x01
+)
+
+// MAX31855Driver is a driver for the MAX31855 thermocouple reader.
+// The chip is read only: every read returns 32 bits with the last conversion done.
+type MAX31855Driver struct {
+	*Driver
+	mu     *sync.Mutex
+	logger commoninterface.Logger
+}
+
+// NewMAX31855Driver creates a new Gobot Driver for MAX31855 thermocouple reader
+//
+// Params:
+//
+//	a *Adaptor - the Adaptor to use with this Driver
+//
+// Optional params:
+//
+//	 spi.WithBusNumber(int):  bus to use with this driver
+//		spi.WithChipNumber(int): chip to use with this driver
+//	 spi.WithSpeed(int64):    speed in Hz to use with this driver
+func NewMAX31855Driver(a spi.Connector, options ...func(spi.Config)) *MAX31855Driver {
+	return &MAX31855Driver{
+		Driver: NewDriver(a, "MAX31855", append([]func(spi.Config){spi.WithMode(0)}, options...)...),
+		mu:     &sync.Mutex{},
+		logger: slog.Default(),
+	}
+}
+
+func (d *MAX31855Driver) SetLogger(logger commoninterface.Logger) {
+	d.logger = logger
+}
+
+// GetTemperature reads the thermocouple temperature in degrees Celsius.
+func (d *MAX31855Driver) GetTemperature() (float64, error) {
+	raw, err := d.read()
+	if err != nil {
+		return 0, err
+	}
+	// 14 bit signed value in the upper bits, with 0.25 degrees per bit
+	return float64(int32(raw)>>18) * 0.25, nil
+}
+
+// GetColdJunctionTemperature reads the internal (cold junction) temperature in degrees Celsius.
+func (d *MAX31855Driver) GetColdJunctionTemperature() (float64, error) {
+	raw, err := d.read()
+	if err != nil {
+		return 0, err
+	}
+	// 12 bit signed value in bits 15:4, with 0.0625 degrees per bit
+	return float64(int32(raw<<16)>>20) * 0.0625, nil
+}
+
+func (d *MAX31855Driver) read() (uint32, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.connection == nil {
+		return 0, fmt.Errorf("cannot get data from device")
+	}
+	data := make([]byte, 4)
+	if err := d.connection.ReadCommandData(make([]byte, 4), data); err != nil {
+		return 0, err
+	}
+	raw := uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	if raw&MAX31855_FAULT != 0 {
+		switch {
+		case raw&MAX31855_FAULT_OC != 0:
+			return raw, fmt.Errorf("thermocouple open circuit")
+		case raw&MAX31855_FAULT_SCG != 0:
+			return raw, fmt.Errorf("thermocouple short circuit to GND")
+		case raw&MAX31855_FAULT_SCV != 0:
+			return raw, fmt.Errorf("thermocouple short circuit to VCC")
+		default:
+			return raw, fmt.Errorf("thermocouple fault")
+		}
+	}
+	return raw, nil
+}
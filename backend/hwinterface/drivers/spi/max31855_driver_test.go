@@ -0,0 +1,95 @@
+package spi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi"
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi/spifake"
+)
+
+// max31855Frame packs the temperatures and the fault bits as the MAX31855 sends them
+func max31855Frame(temperature, coldJunction float64, faults uint32) []byte {
+	raw := uint32(int32(temperature*4))<<18 | (uint32(int32(coldJunction*16))&0x0FFF)<<4 | faults
+	if faults != 0 {
+		raw |= spi.MAX31855_FAULT
+	}
+	return []byte{byte(raw >> 24), byte(raw >> 16), byte(raw >> 8), byte(raw)}
+}
+
+func TestMAX31855(t *testing.T) {
+	tests := []struct {
+		name                      string
+		temperature, coldJunction float64
+		faults                    uint32
+		wantErr                   bool
+	}{
+		{name: "room", temperature: 25, coldJunction: 25},
+		{name: "firing", temperature: 1250.75, coldJunction: 31.0625},
+		{name: "negative", temperature: -200.25, coldJunction: -10.5},
+		{name: "open", temperature: 0, coldJunction: 25, faults: spi.MAX31855_FAULT_OC, wantErr: true},
+		{name: "short to GND", temperature: 0, coldJunction: 25, faults: spi.MAX31855_FAULT_SCG, wantErr: true},
+		{name: "short to VCC", temperature: 0, coldJunction: 25, faults: spi.MAX31855_FAULT_SCV, wantErr: true},
+	}
+	connector := spifake.NewConnector()
+	d := spi.NewMAX31855Driver(connector)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	conn := connector.Connection(0, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn.SetCommandResponse(max31855Frame(tt.temperature, tt.coldJunction, tt.faults)...)
+			got, err := d.GetTemperature()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetTemperature = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetTemperature: %v", err)
+			}
+			if got != tt.temperature {
+				t.Errorf("GetTemperature = %v, want %v", got, tt.temperature)
+			}
+			if got, err := d.GetColdJunctionTemperature(); err != nil || got != tt.coldJunction {
+				t.Errorf("GetColdJunctionTemperature = %v, %v, want %v", got, err, tt.coldJunction)
+			}
+		})
+	}
+}
+
+func TestMAX6675(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   []byte
+		want    float64
+		wantErr bool
+	}{
+		{name: "room", frame: []byte{0x03, 0x20}, want: 25},
+		{name: "hot", frame: []byte{0x7D, 0x00}, want: 1000},
+		{name: "top of range", frame: []byte{0x7F, 0xF8}, want: 1023.75},
+		{name: "open", frame: []byte{0x00, 0x04}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := spifake.NewConnector()
+			d := spi.NewMAX6675Driver(connector)
+			if err := d.Start(); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			connector.Connection(0, 0).SetCommandResponse(tt.frame...)
+			got, err := d.GetTemperature()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("GetTemperature error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("GetTemperature = %v, want %v", got, tt.want)
+			}
+			if _, err := d.GetColdJunctionTemperature(); !errors.Is(err, spi.ErrNotSupported) {
+				t.Errorf("GetColdJunctionTemperature error = %v, want %v", err, spi.ErrNotSupported)
+			}
+		})
+	}
+}
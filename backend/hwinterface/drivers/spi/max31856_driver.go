@@ -3,6 +3,7 @@ package spi
 import (
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,10 +18,34 @@ type MAX31856Driver struct {
 	averageSample, noiseRejectionFrequency int
 	mu                                     *sync.Mutex
 	logger                                 commoninterface.Logger
+
+	autoConversion   bool
+	sampleInterval   time.Duration
+	sampleBufferSize int
+	samples          *sampleRing
+	samplesMu        sync.RWMutex
+	//samplerMu serializes the start and the stop of the sampler
+	samplerMu                sync.Mutex
+	stopSampler, samplerDone chan struct{}
+	//lastConversion is the last one-shot conversion, its cold junction is read with the thermocouple
+	lastConversion Sample
 }
 
+// coldJunctionMaxAge is how long the cold junction of the last one-shot conversion is returned
+// before a new conversion is done
+const coldJunctionMaxAge = 5 * time.Second
+
+// FaultState is the content of the fault status register
 type FaultState struct {
-	Highthresh, Lowthresh, Refinlow, Refinhigh, Rtdinlow, Ovuv bool
+	ColdJunctionRange, ThermocoupleRange bool
+	ColdJunctionHigh, ColdJunctionLow    bool
+	ThermocoupleHigh, ThermocoupleLow    bool
+	OverUnderVoltage, Open               bool
+}
+
+// ThresholdTripped returns true if one of the programmed temperature thresholds has been crossed
+func (f FaultState) ThresholdTripped() bool {
+	return f.ColdJunctionHigh || f.ColdJunctionLow || f.ThermocoupleHigh || f.ThermocoupleLow
 }
 
 func (d *MAX31856Driver) SetLogger(logger commoninterface.Logger) {
@@ -81,6 +106,19 @@ const (
 	G32 ThermocoupleType = 0b1100
 )
 
+var thermocoupleTypeNames = map[string]ThermocoupleType{
+	"B": B, "E": E, "J": J, "K": K, "N": N, "R": R, "S": S, "T": T, "G8": G8, "G32": G32,
+}
+
+// ParseThermocoupleType returns the thermocouple type given its name (K, N...)
+func ParseThermocoupleType(name string) (ThermocoupleType, error) {
+	t, ok := thermocoupleTypeNames[strings.ToUpper(name)]
+	if !ok {
+		return K, fmt.Errorf("invalid thermocouple type %s", name)
+	}
+	return t, nil
+}
+
 // NewMAX31856Driver creates a new Gobot Driver for MAX31856 thermocouple reader
 //
 // Params:
@@ -114,9 +152,15 @@ func NewMAX31856Driver(a spi.Connector, options ...func(*MAX31856Driver)) *MAX31
 		d.writeUint8(MAX31856_CR0_REG, MAX31856_CR0_OCFAULT0)
 		d.SetThermocoupleType(d.thermocoupleType)
 		d.SetAverageSample(d.averageSample)
-		d.SetNoiseRejection(50)
+		d.SetNoiseRejection(d.noiseRejectionFrequency)
+		if d.autoConversion {
+			return d.StartAutoConversion(d.sampleInterval, d.sampleBufferSize)
+		}
 		return nil
 	}
+	d.beforeHalt = func() error {
+		return d.StopAutoConversion()
+	}
 
 	return d
 }
@@ -125,6 +169,15 @@ func WithLogger(logger commoninterface.Logger) func(*MAX31856Driver) {
 		driver.SetLogger(logger)
 	}
 }
+
+// WithSpiConfig applies the spi options (bus, chip, speed) to the driver
+func WithSpiConfig(options ...func(spi.Config)) func(*MAX31856Driver) {
+	return func(driver *MAX31856Driver) {
+		for _, option := range options {
+			option(driver.Driver)
+		}
+	}
+}
 func WithThermocoupleType(t ThermocoupleType) func(*MAX31856Driver) {
 	return func(driver *MAX31856Driver) {
 		driver.thermocoupleType = t
@@ -201,7 +254,7 @@ func (d *MAX31856Driver) InitOneShotMeasurement() error {
 		return err
 	}
 	// and the complement to guarantee the autoconvert bit is unset
-	confReg0 |= (MAX31856_CR0_AUTOCONVERT)
+	confReg0 &= ^MAX31856_CR0_AUTOCONVERT
 	// or the oneshot bit to ensure it is set
 	confReg0 |= MAX31856_CR0_1SHOT
 
@@ -235,15 +288,68 @@ func (d *MAX31856Driver) performOneShotMeasurement() error {
 }
 
 // GetTemperature: Measure the temperature of the sensor and wait for the result.
+// If the driver is in auto-conversion mode, the last sample is returned instead.
 //
 //	Return value is in degrees Celsius.
 func (d *MAX31856Driver) GetTemperature() (float64, error) {
+	if d.IsAutoConverting() {
+		sample, err := d.latestFreshSample()
+		return sample.Temperature, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sample, err := d.oneShotSample()
+	return sample.Temperature, err
+}
+
+// GetColdJunctionTemperature: Measure the temperature of the cold junction (the chip itself) and wait for the result.
+// If the driver is in auto-conversion mode, the last sample is returned instead. In one-shot mode the cold junction of
+// the last conversion is returned if it is recent, so a read of both temperatures needs a single conversion.
+//
+//	Return value is in degrees Celsius.
+func (d *MAX31856Driver) GetColdJunctionTemperature() (float64, error) {
+	if d.IsAutoConverting() {
+		sample, err := d.latestFreshSample()
+		return sample.ColdJunctionTemperature, err
+	}
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	if !d.lastConversion.Time.IsZero() && d.lastConversion.Age() <= coldJunctionMaxAge {
+		return d.lastConversion.ColdJunctionTemperature, nil
+	}
+	sample, err := d.oneShotSample()
+	return sample.ColdJunctionTemperature, err
+}
+
+// oneShotSample does a conversion and reads both the thermocouple and the cold junction, keeping them as the last conversion.
+// It must be called with d.mu locked.
+func (d *MAX31856Driver) oneShotSample() (Sample, error) {
 	if err := d.performOneShotMeasurement(); err != nil {
+		return Sample{}, err
+	}
+	sample := Sample{Time: time.Now()}
+	if sample.Temperature, sample.Err = d.UnpackTemperature(); sample.Err != nil {
+		return sample, sample.Err
+	}
+	if sample.ColdJunctionTemperature, sample.Err = d.UnpackColdJunctionTemperature(); sample.Err != nil {
+		return sample, sample.Err
+	}
+	d.lastConversion = sample
+	return sample, nil
+}
+
+// Reads the cold junction temperature from the registers
+func (d *MAX31856Driver) UnpackColdJunctionTemperature() (float64, error) {
+	rawTemp := make([]byte, 2)
+	if d.connection == nil {
+		return 0, fmt.Errorf("cannot get data from device")
+	}
+	if err := d.connection.ReadBlockData(MAX31856_CJTH_REG, rawTemp); err != nil {
 		return 0, err
 	}
-	return d.UnpackTemperature()
+	// 14 bit signed value, left aligned, with 0.015625 degrees per bit
+	tempInt := twosComplement16Bit((uint16(rawTemp[0]) << 8) | uint16(rawTemp[1]))
+	return float64(tempInt>>2) * 0.015625, nil
 }
 
 // Reads the probe temperature from the register
@@ -252,10 +358,13 @@ func (d *MAX31856Driver) UnpackTemperature() (float64, error) {
 	if d.connection == nil {
 		return 0, fmt.Errorf("cannot get data from device")
 	}
-	d.connection.ReadBlockData(MAX31856_LTCBH_REG, rawTemp)
+	if err := d.connection.ReadBlockData(MAX31856_LTCBH_REG, rawTemp); err != nil {
+		return 0, err
+	}
 
-	// effectively shift raw_read >> 12 to convert pseudo-float
-	tempInt := (uint32(rawTemp[0]) << 16) | (uint32(rawTemp[1]) << 8) | uint32(rawTemp[2])
+	// 19 bit signed value, left aligned in 24 bits, with 0.0078125 degrees per bit:
+	// sign extend the 24 bits and shift raw_read >> 12 to convert pseudo-float
+	tempInt := int32((uint32(rawTemp[0])<<24)|(uint32(rawTemp[1])<<16)|(uint32(rawTemp[2])<<8)) >> 8
 	return float64(tempInt) / 4096.0, nil
 }
 
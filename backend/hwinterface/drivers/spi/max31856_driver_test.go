@@ -0,0 +1,289 @@
+package spi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi"
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi/spifake"
+)
+
+// startMAX31856 returns a started driver reading the simulated chip
+func startMAX31856(t *testing.T, options ...func(*spi.MAX31856Driver)) (*spi.MAX31856Driver, *spifake.MAX31856) {
+	t.Helper()
+	connector := spifake.NewConnector()
+	chip := spifake.NewMAX31856()
+	connector.Attach(0, 0, chip)
+	d := spi.NewMAX31856Driver(connector, options...)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { d.Halt() })
+	return d, chip
+}
+
+func TestMAX31856Configuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []func(*spi.MAX31856Driver)
+		cr0, cr1 uint8
+	}{
+		{"defaults", nil, spi.MAX31856_CR0_OCFAULT0 | spi.MAX31856_CR0_50HZ, 0x03},
+		{"type N", []func(*spi.MAX31856Driver){spi.WithThermocoupleType(spi.N)},
+			spi.MAX31856_CR0_OCFAULT0 | spi.MAX31856_CR0_50HZ, 0x04},
+		{"type S, 4 samples", []func(*spi.MAX31856Driver){spi.WithThermocoupleType(spi.S), spi.WithAverageSample(4)},
+			spi.MAX31856_CR0_OCFAULT0 | spi.MAX31856_CR0_50HZ, 0x26},
+		{"16 samples at 60Hz", []func(*spi.MAX31856Driver){spi.WithAverageSample(16), spi.WithNoiseRejection(60)},
+			spi.MAX31856_CR0_OCFAULT0, 0x43},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, chip := startMAX31856(t, tt.options...)
+			if got := chip.Register(spi.MAX31856_CR0_REG); got != tt.cr0 {
+				t.Errorf("CR0 = %#02x, want %#02x", got, tt.cr0)
+			}
+			if got := chip.Register(spi.MAX31856_CR1_REG); got != tt.cr1 {
+				t.Errorf("CR1 = %#02x, want %#02x", got, tt.cr1)
+			}
+			if got := chip.Register(spi.MAX31856_MASK_REG); got != 0 {
+				t.Errorf("MASK = %#02x, want 0", got)
+			}
+		})
+	}
+}
+
+func TestMAX31856InvalidConfiguration(t *testing.T) {
+	d, chip := startMAX31856(t)
+	if err := d.SetAverageSample(3); err == nil {
+		t.Error("SetAverageSample(3) should fail")
+	}
+	if err := d.SetNoiseRejection(55); err == nil {
+		t.Error("SetNoiseRejection(55) should fail")
+	}
+	if got := chip.Register(spi.MAX31856_CR1_REG); got != 0x03 {
+		t.Errorf("CR1 changed by invalid settings: %#02x", got)
+	}
+	if _, err := spi.ParseThermocoupleType("X"); err == nil {
+		t.Error("ParseThermocoupleType(X) should fail")
+	}
+	if tc, err := spi.ParseThermocoupleType("g32"); err != nil || tc != spi.G32 {
+		t.Errorf("ParseThermocoupleType(g32) = %v, %v", tc, err)
+	}
+}
+
+func TestMAX31856Temperatures(t *testing.T) {
+	tests := []struct {
+		name                      string
+		temperature, coldJunction float64
+	}{
+		{"room", 25, 25},
+		{"firing", 1250.5, 31.25},
+		{"zero", 0, 0},
+		{"smallest negative step", -0.0078125, -0.015625},
+		{"negative", -200.25, -10.5},
+		{"fraction", 1000.0078125, 0.015625},
+		{"top of type B", 1800, 125},
+	}
+	d, chip := startMAX31856(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chip.SetTemperature(tt.temperature)
+			chip.SetColdJunctionTemperature(tt.coldJunction)
+			got, err := d.GetTemperature()
+			if err != nil {
+				t.Fatalf("GetTemperature: %v", err)
+			}
+			if got != tt.temperature {
+				t.Errorf("GetTemperature = %v, want %v", got, tt.temperature)
+			}
+			got, err = d.GetColdJunctionTemperature()
+			if err != nil {
+				t.Fatalf("GetColdJunctionTemperature: %v", err)
+			}
+			if got != tt.coldJunction {
+				t.Errorf("GetColdJunctionTemperature = %v, want %v", got, tt.coldJunction)
+			}
+		})
+	}
+}
+
+func TestMAX31856ReadError(t *testing.T) {
+	d, chip := startMAX31856(t)
+	readErr := errors.New("bus error")
+	chip.SetError(readErr)
+	if _, err := d.GetTemperature(); !errors.Is(err, readErr) {
+		t.Errorf("GetTemperature error = %v, want %v", err, readErr)
+	}
+	if _, err := d.ReadFault(); !errors.Is(err, readErr) {
+		t.Errorf("ReadFault error = %v, want %v", err, readErr)
+	}
+}
+
+func TestMAX31856Faults(t *testing.T) {
+	tests := []struct {
+		name                      string
+		temperature, coldJunction float64
+		open                      bool
+		want                      spi.FaultState
+		tripped                   bool
+	}{
+		{name: "no fault", temperature: 500, coldJunction: 25},
+		{name: "open", temperature: 500, coldJunction: 25, open: true, want: spi.FaultState{Open: true}},
+		{name: "thermocouple high", temperature: 1300.5, coldJunction: 25,
+			want: spi.FaultState{ThermocoupleHigh: true}, tripped: true},
+		{name: "thermocouple low", temperature: -20, coldJunction: 25,
+			want: spi.FaultState{ThermocoupleLow: true}, tripped: true},
+		{name: "cold junction high", temperature: 500, coldJunction: 71,
+			want: spi.FaultState{ColdJunctionHigh: true}, tripped: true},
+		{name: "cold junction low", temperature: 500, coldJunction: -6,
+			want: spi.FaultState{ColdJunctionLow: true}, tripped: true},
+	}
+	d, chip := startMAX31856(t)
+	if err := d.SetTemperatureThresholds(-10, 1300); err != nil {
+		t.Fatalf("SetTemperatureThresholds: %v", err)
+	}
+	if err := d.SetColdJunctionThresholds(-5, 70); err != nil {
+		t.Fatalf("SetColdJunctionThresholds: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chip.SetTemperature(tt.temperature)
+			chip.SetColdJunctionTemperature(tt.coldJunction)
+			chip.SetOpen(tt.open)
+			if _, err := d.GetTemperature(); err != nil {
+				t.Fatalf("GetTemperature: %v", err)
+			}
+			got, err := d.ReadFault()
+			if err != nil {
+				t.Fatalf("ReadFault: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadFault = %+v, want %+v", got, tt.want)
+			}
+			if got.ThresholdTripped() != tt.tripped {
+				t.Errorf("ThresholdTripped = %v, want %v", got.ThresholdTripped(), tt.tripped)
+			}
+		})
+	}
+}
+
+func TestMAX31856OneShotTiming(t *testing.T) {
+	const conversionTime = 50 * time.Millisecond
+	d, chip := startMAX31856(t)
+	chip.SetConversionTime(conversionTime)
+	chip.SetTemperature(850)
+	chip.SetColdJunctionTemperature(30)
+
+	start := time.Now()
+	got, err := d.GetTemperature()
+	if err != nil {
+		t.Fatalf("GetTemperature: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < conversionTime {
+		t.Errorf("GetTemperature returned after %v, before the conversion ended", elapsed)
+	}
+	if got != 850 {
+		t.Errorf("GetTemperature = %v, want 850", got)
+	}
+	if d.OneShotPending() {
+		t.Error("one-shot still pending after the read")
+	}
+	if cr0 := chip.Register(spi.MAX31856_CR0_REG); cr0&spi.MAX31856_CR0_AUTOCONVERT != 0 {
+		t.Errorf("auto-conversion set by a one-shot read, CR0 = %#02x", cr0)
+	}
+
+	//the cold junction comes from the same conversion
+	cj, err := d.GetColdJunctionTemperature()
+	if err != nil {
+		t.Fatalf("GetColdJunctionTemperature: %v", err)
+	}
+	if cj != 30 {
+		t.Errorf("GetColdJunctionTemperature = %v, want 30", cj)
+	}
+	if n := chip.Conversions(); n != 1 {
+		t.Errorf("%d conversions for a thermocouple and cold junction read, want 1", n)
+	}
+
+	if err := d.InitOneShotMeasurement(); err != nil {
+		t.Fatalf("InitOneShotMeasurement: %v", err)
+	}
+	if !d.OneShotPending() {
+		t.Error("one-shot not pending right after the request")
+	}
+	time.Sleep(conversionTime)
+	if d.OneShotPending() {
+		t.Error("one-shot still pending after the conversion time")
+	}
+}
+
+func TestMAX31856TemperatureThresholds(t *testing.T) {
+	tests := []struct {
+		name               string
+		low, high          float64
+		wantLow, wantHigh  float64
+		rawHigh0, rawHigh1 uint8
+		wantErr            bool
+	}{
+		{name: "kiln range", low: -100, high: 1300, wantLow: -100, wantHigh: 1300, rawHigh0: 0x51, rawHigh1: 0x40},
+		{name: "sixteenths", low: -0.0625, high: 1300.0625, wantLow: -0.0625, wantHigh: 1300.0625, rawHigh0: 0x51, rawHigh1: 0x41},
+		{name: "rounded", low: 0.03, high: 100.04, wantLow: 0, wantHigh: 100.0625, rawHigh0: 0x06, rawHigh1: 0x41},
+		{name: "clamped", low: -3000, high: 3000, wantLow: -2048, wantHigh: 2047.9375, rawHigh0: 0x7F, rawHigh1: 0xFF},
+		{name: "low over high", low: 500, high: 100, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, chip := startMAX31856(t)
+			err := d.SetTemperatureThresholds(tt.low, tt.high)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("SetTemperatureThresholds should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetTemperatureThresholds: %v", err)
+			}
+			if h0, h1 := chip.Register(spi.MAX31856_LTHFTH_REG), chip.Register(spi.MAX31856_LTHFTL_REG); h0 != tt.rawHigh0 || h1 != tt.rawHigh1 {
+				t.Errorf("high threshold registers = %#02x %#02x, want %#02x %#02x", h0, h1, tt.rawHigh0, tt.rawHigh1)
+			}
+			low, high, err := d.GetTemperatureThresholds()
+			if err != nil {
+				t.Fatalf("GetTemperatureThresholds: %v", err)
+			}
+			if low != tt.wantLow || high != tt.wantHigh {
+				t.Errorf("GetTemperatureThresholds = %v, %v, want %v, %v", low, high, tt.wantLow, tt.wantHigh)
+			}
+		})
+	}
+}
+
+func TestMAX31856ColdJunctionThresholds(t *testing.T) {
+	d, chip := startMAX31856(t)
+	if err := d.SetColdJunctionThresholds(-20, 85); err != nil {
+		t.Fatalf("SetColdJunctionThresholds: %v", err)
+	}
+	if l := chip.Register(spi.MAX31856_CJLF_REG); l != 0xEC {
+		t.Errorf("CJLF = %#02x, want 0xec", l)
+	}
+	low, high, err := d.GetColdJunctionThresholds()
+	if err != nil || low != -20 || high != 85 {
+		t.Errorf("GetColdJunctionThresholds = %d, %d, %v, want -20, 85", low, high, err)
+	}
+	if err := d.SetColdJunctionThresholds(10, 10); err == nil {
+		t.Error("SetColdJunctionThresholds with low equal to high should fail")
+	}
+
+	if err := d.ResetColdJunctionThresholds(); err != nil {
+		t.Fatalf("ResetColdJunctionThresholds: %v", err)
+	}
+	if low, high, _ := d.GetColdJunctionThresholds(); low != -64 || high != 127 {
+		t.Errorf("reset cold junction thresholds = %d, %d, want -64, 127", low, high)
+	}
+	if err := d.ResetTemperatureThresholds(); err != nil {
+		t.Fatalf("ResetTemperatureThresholds: %v", err)
+	}
+	if low, high, _ := d.GetTemperatureThresholds(); low != -2048 || high != 2047.9375 {
+		t.Errorf("reset thresholds = %v, %v, want -2048, 2047.9375", low, high)
+	}
+}
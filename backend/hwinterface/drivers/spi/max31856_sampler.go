@@ -0,0 +1,206 @@
+package spi
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sample is a reading taken by the background sampler while the chip is in auto-conversion mode.
+type Sample struct {
+	Temperature             float64
+	ColdJunctionTemperature float64
+	Time                    time.Time
+	Err                     error
+}
+
+// Age returns how old the sample is
+func (s Sample) Age() time.Duration {
+	return time.Since(s.Time)
+}
+
+// sampleRing is a fixed size ring buffer of the last samples read
+type sampleRing struct {
+	samples []Sample
+	next    int
+	full    bool
+}
+
+func newSampleRing(size int) *sampleRing {
+	if size < 1 {
+		size = 1
+	}
+	return &sampleRing{samples: make([]Sample, size)}
+}
+
+func (r *sampleRing) push(s Sample) {
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *sampleRing) latest() (Sample, bool) {
+	if !r.full && r.next == 0 {
+		return Sample{}, false
+	}
+	return r.samples[(r.next+len(r.samples)-1)%len(r.samples)], true
+}
+
+// all returns the samples from the oldest to the newest
+func (r *sampleRing) all() []Sample {
+	if !r.full {
+		return append([]Sample(nil), r.samples[:r.next]...)
+	}
+	return append(append([]Sample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// WithAutoConversion makes the driver start the background sampler when started
+func WithAutoConversion(interval time.Duration, bufferSize int) func(*MAX31856Driver) {
+	return func(driver *MAX31856Driver) {
+		driver.autoConversion = true
+		driver.sampleInterval = interval
+		driver.sampleBufferSize = bufferSize
+	}
+}
+
+// StartAutoConversion puts the chip in auto-conversion mode (a conversion every ~100ms)
+// and starts a goroutine that reads the registers every interval, keeping the last bufferSize samples.
+// If the sampler is already running it is restarted with the new parameters.
+func (d *MAX31856Driver) StartAutoConversion(interval time.Duration, bufferSize int) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid sample interval")
+	}
+	d.samplerMu.Lock()
+	defer d.samplerMu.Unlock()
+	d.stopAutoConversion()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	confReg0, err := d.readUint8(MAX31856_CR0_REG)
+	if err != nil {
+		return err
+	}
+	confReg0 &= ^MAX31856_CR0_1SHOT
+	confReg0 |= MAX31856_CR0_AUTOCONVERT
+	if err := d.writeUint8(MAX31856_CR0_REG, confReg0); err != nil {
+		return err
+	}
+
+	d.samplesMu.Lock()
+	d.autoConversion = true
+	d.sampleInterval = interval
+	d.sampleBufferSize = bufferSize
+	d.samples = newSampleRing(bufferSize)
+	stop, done := make(chan struct{}), make(chan struct{})
+	d.stopSampler, d.samplerDone = stop, done
+	d.samplesMu.Unlock()
+
+	go d.runSampler(interval, stop, done)
+	return nil
+}
+
+// StopAutoConversion stops the background sampler and puts the chip back in normally-off (one-shot) mode.
+func (d *MAX31856Driver) StopAutoConversion() error {
+	d.samplerMu.Lock()
+	defer d.samplerMu.Unlock()
+	return d.stopAutoConversion()
+}
+
+// stopAutoConversion stops the sampler, it must be called with d.samplerMu locked
+func (d *MAX31856Driver) stopAutoConversion() error {
+	d.samplesMu.Lock()
+	stop, done := d.stopSampler, d.samplerDone
+	d.stopSampler, d.samplerDone = nil, nil
+	d.autoConversion = false
+	d.samplesMu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+	<-done
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	confReg0, err := d.readUint8(MAX31856_CR0_REG)
+	if err != nil {
+		return err
+	}
+	confReg0 &= ^MAX31856_CR0_AUTOCONVERT
+	return d.writeUint8(MAX31856_CR0_REG, confReg0)
+}
+
+// IsAutoConverting returns true if the background sampler is running
+func (d *MAX31856Driver) IsAutoConverting() bool {
+	d.samplesMu.RLock()
+	defer d.samplesMu.RUnlock()
+	return d.stopSampler != nil
+}
+
+// LatestSample returns the last sample read by the background sampler.
+func (d *MAX31856Driver) LatestSample() (Sample, error) {
+	d.samplesMu.RLock()
+	defer d.samplesMu.RUnlock()
+	if d.samples == nil {
+		return Sample{}, fmt.Errorf("auto conversion not started")
+	}
+	sample, ok := d.samples.latest()
+	if !ok {
+		return Sample{}, fmt.Errorf("no sample available yet")
+	}
+	return sample, nil
+}
+
+// Samples returns all the samples in the buffer, from the oldest to the newest
+func (d *MAX31856Driver) Samples() []Sample {
+	d.samplesMu.RLock()
+	defer d.samplesMu.RUnlock()
+	if d.samples == nil {
+		return nil
+	}
+	return d.samples.all()
+}
+
+// latestFreshSample returns the last sample, with an error if the sample is too old to be trusted
+func (d *MAX31856Driver) latestFreshSample() (Sample, error) {
+	sample, err := d.LatestSample()
+	if err != nil {
+		return sample, err
+	}
+	if sample.Err != nil {
+		return sample, sample.Err
+	}
+	d.samplesMu.RLock()
+	maxAge := max(5*d.sampleInterval, time.Second)
+	d.samplesMu.RUnlock()
+	if sample.Age() > maxAge {
+		return sample, fmt.Errorf("last sample is too old: %v", sample.Age())
+	}
+	return sample, nil
+}
+
+func (d *MAX31856Driver) runSampler(interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			sample := Sample{Time: now}
+			sample.Temperature, sample.Err = d.UnpackTemperature()
+			if sample.Err == nil {
+				sample.ColdJunctionTemperature, sample.Err = d.UnpackColdJunctionTemperature()
+			}
+			d.mu.Unlock()
+			if sample.Err != nil {
+				d.logger.Error("MAX31856 sampler error", "err", sample.Err)
+			}
+			d.samplesMu.Lock()
+			d.samples.push(sample)
+			d.samplesMu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package spi
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// values of the threshold registers at power on, so that no threshold fault is ever raised
+	defaultThermocoupleHighThreshold float64 = 2047.9375
+	defaultThermocoupleLowThreshold  float64 = -2048
+	defaultColdJunctionHighThreshold int8    = 127
+	defaultColdJunctionLowThreshold  int8    = -64
+)
+
+// SetTemperatureThresholds programs the thermocouple low and high fault thresholds, in degrees Celsius.
+// When the temperature goes out of the range the chip raises the TCLOW/TCHIGH fault.
+func (d *MAX31856Driver) SetTemperatureThresholds(low, high float64) error {
+	if low >= high {
+		return fmt.Errorf("invalid thresholds: low %.2f must be lower than high %.2f", low, high)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.writeThreshold16(MAX31856_LTHFTH_REG, high); err != nil {
+		return err
+	}
+	return d.writeThreshold16(MAX31856_LTLFTH_REG, low)
+}
+
+// ResetTemperatureThresholds sets the thermocouple thresholds to the power on values (no fault).
+func (d *MAX31856Driver) ResetTemperatureThresholds() error {
+	return d.SetTemperatureThresholds(defaultThermocoupleLowThreshold, defaultThermocoupleHighThreshold)
+}
+
+// GetTemperatureThresholds reads back the thermocouple low and high fault thresholds, in degrees Celsius.
+func (d *MAX31856Driver) GetTemperatureThresholds() (low, high float64, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if high, err = d.readThreshold16(MAX31856_LTHFTH_REG); err != nil {
+		return
+	}
+	low, err = d.readThreshold16(MAX31856_LTLFTH_REG)
+	return
+}
+
+// SetColdJunctionThresholds programs the cold junction low and high fault thresholds, in degrees Celsius.
+func (d *MAX31856Driver) SetColdJunctionThresholds(low, high int8) error {
+	if low >= high {
+		return fmt.Errorf("invalid cold junction thresholds: low %d must be lower than high %d", low, high)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.writeUint8(MAX31856_CJHF_REG, uint8(high)); err != nil {
+		return err
+	}
+	return d.writeUint8(MAX31856_CJLF_REG, uint8(low))
+}
+
+// ResetColdJunctionThresholds sets the cold junction thresholds to the power on values (no fault).
+func (d *MAX31856Driver) ResetColdJunctionThresholds() error {
+	return d.SetColdJunctionThresholds(defaultColdJunctionLowThreshold, defaultColdJunctionHighThreshold)
+}
+
+// GetColdJunctionThresholds reads back the cold junction low and high fault thresholds, in degrees Celsius.
+func (d *MAX31856Driver) GetColdJunctionThresholds() (low, high int8, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, err := d.readUint8(MAX31856_CJHF_REG)
+	if err != nil {
+		return
+	}
+	l, err := d.readUint8(MAX31856_CJLF_REG)
+	if err != nil {
+		return
+	}
+	return int8(l), int8(h), nil
+}
+
+// ReadFault reads the fault status register
+func (d *MAX31856Driver) ReadFault() (FaultState, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status, err := d.readUint8(MAX31856_SR_REG)
+	if err != nil {
+		return FaultState{}, err
+	}
+	return FaultState{
+		ColdJunctionRange: status&MAX31856_FAULT_CJRANGE != 0,
+		ThermocoupleRange: status&MAX31856_FAULT_TCRANGE != 0,
+		ColdJunctionHigh:  status&MAX31856_FAULT_CJHIGH != 0,
+		ColdJunctionLow:   status&MAX31856_FAULT_CJLOW != 0,
+		ThermocoupleHigh:  status&MAX31856_FAULT_TCHIGH != 0,
+		ThermocoupleLow:   status&MAX31856_FAULT_TCLOW != 0,
+		OverUnderVoltage:  status&MAX31856_FAULT_OVUV != 0,
+		Open:              status&MAX31856_FAULT_OPEN != 0,
+	}, nil
+}
+
+// writeThreshold16 writes a temperature in the two registers starting from highAddress,
+// as a 16 bit signed value with 0.0625 degrees per bit
+func (d *MAX31856Driver) writeThreshold16(highAddress uint8, temperature float64) error {
+	raw := uint16(int16(math.Round(math.Max(math.Min(temperature, defaultThermocoupleHighThreshold), defaultThermocoupleLowThreshold) * 16)))
+	if err := d.writeUint8(highAddress, uint8(raw>>8)); err != nil {
+		return err
+	}
+	return d.writeUint8(highAddress+1, uint8(raw))
+}
+
+func (d *MAX31856Driver) readThreshold16(highAddress uint8) (float64, error) {
+	h, err := d.readUint8(highAddress)
+	if err != nil {
+		return 0, err
+	}
+	l, err := d.readUint8(highAddress + 1)
+	if err != nil {
+		return 0, err
+	}
+	return float64(twosComplement16Bit(uint16(h)<<8|uint16(l))) / 16, nil
+}
@@ -0,0 +1,83 @@
+package spi
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"gobot.io/x/gobot/v2/drivers/spi"
+)
+
+const (
+	MAX6675_OPEN uint16 = 0x04
+	// a conversion takes up to 220ms, reading before aborts it
+	max6675ConversionTime = 220 * time.Millisecond
+)
+
+// ErrNotSupported is returned when a reading is not available on the device
+var ErrNotSupported = commoninterface.ErrNotSupported
+
+// MAX6675Driver is a driver for the MAX6675 K thermocouple reader.
+// The chip is read only: every read returns 16 bits with the last conversion done.
+type MAX6675Driver struct {
+	*Driver
+	mu              *sync.Mutex
+	logger          commoninterface.Logger
+	lastRead        time.Time
+	lastTemperature float64
+}
+
+// NewMAX6675Driver creates a new Gobot Driver for MAX6675 thermocouple reader
+//
+// Params:
+//
+//	a *Adaptor - the Adaptor to use with this Driver
+//
+// Optional params:
+//
+//	 spi.WithBusNumber(int):  bus to use with this driver
+//		spi.WithChipNumber(int): chip to use with this driver
+//	 spi.WithSpeed(int64):    speed in Hz to use with this driver
+func NewMAX6675Driver(a spi.Connector, options ...func(spi.Config)) *MAX6675Driver {
+	return &MAX6675Driver{
+		Driver: NewDriver(a, "MAX6675", append([]func(spi.Config){spi.WithMode(0)}, options...)...),
+		mu:     &sync.Mutex{},
+		logger: slog.Default(),
+	}
+}
+
+func (d *MAX6675Driver) SetLogger(logger commoninterface.Logger) {
+	d.logger = logger
+}
+
+// GetTemperature reads the thermocouple temperature in degrees Celsius.
+// Reads closer than the conversion time return the last value, to not abort the running conversion.
+func (d *MAX6675Driver) GetTemperature() (float64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.lastRead.IsZero() && time.Since(d.lastRead) < max6675ConversionTime {
+		return d.lastTemperature, nil
+	}
+	if d.connection == nil {
+		return 0, fmt.Errorf("cannot get data from device")
+	}
+	data := make([]byte, 2)
+	if err := d.connection.ReadCommandData(make([]byte, 2), data); err != nil {
+		return 0, err
+	}
+	d.lastRead = time.Now()
+	raw := uint16(data[0])<<8 | uint16(data[1])
+	if raw&MAX6675_OPEN != 0 {
+		return 0, fmt.Errorf("thermocouple open circuit")
+	}
+	// 12 bit unsigned value in bits 14:3, with 0.25 degrees per bit
+	d.lastTemperature = float64((raw>>3)&0x0FFF) * 0.25
+	return d.lastTemperature, nil
+}
+
+// GetColdJunctionTemperature is not available on MAX6675
+func (d *MAX6675Driver) GetColdJunctionTemperature() (float64, error) {
+	return 0, ErrNotSupported
+}
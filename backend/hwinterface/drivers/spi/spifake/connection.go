@@ -0,0 +1,195 @@
+// Package spifake contains an in-memory spi connection, to use the spi drivers without the hardware.
+package spifake
+
+import (
+	"fmt"
+	"sync"
+
+	"gobot.io/x/gobot/v2/drivers/spi"
+)
+
+// Write is a write operation done on the connection
+type Write struct {
+	Register uint8
+	Data     []byte
+}
+
+// Connection is a fake spi.Connection backed by a register array.
+// Writes are stored at the register address received, so devices that set a write flag
+// in the address (as the MAX31856, with the bit 7) see the written values at Registers[address|flag].
+// ReadCommandData returns CommandResponse, for read only devices (MAX31855, MAX6675).
+type Connection struct {
+	mu              sync.Mutex
+	Registers       [256]byte
+	CommandResponse []byte
+	Writes          []Write
+	Err             error
+	closed          bool
+}
+
+// NewConnection returns an empty connection
+func NewConnection() *Connection {
+	return &Connection{}
+}
+
+// SetRegisters sets the registers starting from the given address
+func (c *Connection) SetRegisters(reg uint8, data ...byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copy(c.Registers[reg:], data)
+}
+
+// SetCommandResponse sets the bytes returned by ReadCommandData
+func (c *Connection) SetCommandResponse(data ...byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.CommandResponse = data
+}
+
+// SetError makes every following operation fail with err (nil to restore)
+func (c *Connection) SetError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Err = err
+}
+
+// Register returns the value of a register
+func (c *Connection) Register(reg uint8) byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Registers[reg]
+}
+
+// AllWrites returns a copy of the writes done
+func (c *Connection) AllWrites() []Write {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Write(nil), c.Writes...)
+}
+
+func (c *Connection) ReadCommandData(command []byte, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	copy(data, c.CommandResponse)
+	return nil
+}
+
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *Connection) ReadByteData(reg uint8) (uint8, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(); err != nil {
+		return 0, err
+	}
+	return c.Registers[reg], nil
+}
+
+func (c *Connection) ReadBlockData(reg uint8, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	for i := range data {
+		data[i] = c.Registers[(int(reg)+i)%len(c.Registers)]
+	}
+	return nil
+}
+
+func (c *Connection) WriteByteData(reg uint8, val uint8) error {
+	return c.WriteBlockData(reg, []byte{val})
+}
+
+func (c *Connection) WriteBlockData(reg uint8, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	c.Writes = append(c.Writes, Write{Register: reg, Data: append([]byte(nil), data...)})
+	for i, v := range data {
+		c.Registers[(int(reg)+i)%len(c.Registers)] = v
+	}
+	return nil
+}
+
+func (c *Connection) WriteByte(val byte) error {
+	return c.WriteBytes([]byte{val})
+}
+
+func (c *Connection) WriteBytes(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return c.WriteBlockData(data[0], data[1:])
+}
+
+func (c *Connection) check() error {
+	if c.closed {
+		return fmt.Errorf("connection closed")
+	}
+	return c.Err
+}
+
+// Connector is a fake spi.Connector returning a Connection for each bus/chip,
+// or the device attached to it
+type Connector struct {
+	mu          sync.Mutex
+	connections map[[2]int]*Connection
+	devices     map[[2]int]spi.Connection
+}
+
+// NewConnector returns a connector with no connections, they are created when requested
+func NewConnector() *Connector {
+	return &Connector{connections: make(map[[2]int]*Connection), devices: make(map[[2]int]spi.Connection)}
+}
+
+// Attach connects a simulated device (as MAX31856) to bus/chip
+func (c *Connector) Attach(busNum, chip int, device spi.Connection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.devices[[2]int{busNum, chip}] = device
+}
+
+// Connection returns the connection for bus/chip, creating it if needed
+func (c *Connector) Connection(busNum, chip int) *Connection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn, ok := c.connections[[2]int{busNum, chip}]
+	if !ok {
+		conn = NewConnection()
+		c.connections[[2]int{busNum, chip}] = conn
+	}
+	return conn
+}
+
+func (c *Connector) GetSpiConnection(busNum, chip, mode, bits int, maxSpeed int64) (spi.Connection, error) {
+	c.mu.Lock()
+	device, ok := c.devices[[2]int{busNum, chip}]
+	c.mu.Unlock()
+	if ok {
+		return device, nil
+	}
+	return c.Connection(busNum, chip), nil
+}
+
+func (c *Connector) SpiDefaultBusNumber() int  { return 0 }
+func (c *Connector) SpiDefaultChipNumber() int { return 0 }
+func (c *Connector) SpiDefaultMode() int       { return 0 }
+func (c *Connector) SpiDefaultBitCount() int   { return 8 }
+func (c *Connector) SpiDefaultMaxSpeed() int64 { return 500000 }
+
+// The connector is also a gobot adaptor, as required by the spi drivers.
+func (c *Connector) Name() string    { return "spifake" }
+func (c *Connector) SetName(string)  {}
+func (c *Connector) Connect() error  { return nil }
+func (c *Connector) Finalize() error { return nil }
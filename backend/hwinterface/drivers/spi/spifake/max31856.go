@@ -0,0 +1,214 @@
+package spifake
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	maxspi "github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi"
+)
+
+// MAX31856 is a simulated MAX31856 thermocouple reader, to attach to a Connector.
+// Register writes use the address with the bit 7 set, as on the chip. A conversion copies the simulated
+// temperatures in the result registers: it is done when a one-shot is requested, and at each read
+// of the result registers while auto-converting. The fault register works in comparator mode.
+type MAX31856 struct {
+	mu           sync.Mutex
+	registers    [16]byte
+	temperature  float64
+	coldJunction float64
+	open         bool
+	err          error
+	writes       []Write
+	//conversionTime is how long a one-shot conversion stays pending, 0 converts at once
+	conversionTime time.Duration
+	pendingUntil   time.Time
+	conversions    int
+}
+
+// NewMAX31856 returns a chip with the power-on register values, reading 25 degrees on both channels
+func NewMAX31856() *MAX31856 {
+	m := &MAX31856{temperature: 25, coldJunction: 25}
+	m.registers[maxspi.MAX31856_CR1_REG] = 0x03
+	m.registers[maxspi.MAX31856_MASK_REG] = 0xFF
+	m.registers[maxspi.MAX31856_CJHF_REG] = 0x7F
+	m.registers[maxspi.MAX31856_CJLF_REG] = 0xC0
+	m.registers[maxspi.MAX31856_LTHFTH_REG] = 0x7F
+	m.registers[maxspi.MAX31856_LTHFTL_REG] = 0xFF
+	m.registers[maxspi.MAX31856_LTLFTH_REG] = 0x80
+	m.registers[maxspi.MAX31856_LTLFTL_REG] = 0x00
+	return m
+}
+
+// SetTemperature sets the thermocouple temperature read by the next conversion
+func (m *MAX31856) SetTemperature(t float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.temperature = t
+}
+
+// SetColdJunctionTemperature sets the chip temperature read by the next conversion
+func (m *MAX31856) SetColdJunctionTemperature(t float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.coldJunction = t
+}
+
+// SetOpen simulates a broken thermocouple
+func (m *MAX31856) SetOpen(open bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.open = open
+}
+
+// SetConversionTime sets how long a one-shot conversion stays pending, as on the chip (about 160ms).
+// With 0, the default, the conversion is done as soon as it is requested.
+func (m *MAX31856) SetConversionTime(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conversionTime = d
+}
+
+// Conversions returns the number of conversions done
+func (m *MAX31856) Conversions() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conversions
+}
+
+// SetError makes every following operation fail with err (nil to restore)
+func (m *MAX31856) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Register returns the value of a register
+func (m *MAX31856) Register(reg uint8) byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.registers[reg&0x0F]
+}
+
+// AllWrites returns a copy of the writes done, with the address as sent (bit 7 set)
+func (m *MAX31856) AllWrites() []Write {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Write(nil), m.writes...)
+}
+
+func (m *MAX31856) ReadByteData(reg uint8) (uint8, error) {
+	data := make([]byte, 1)
+	err := m.ReadBlockData(reg, data)
+	return data[0], err
+}
+
+func (m *MAX31856) ReadBlockData(reg uint8, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if reg&0x80 != 0 {
+		return fmt.Errorf("read from write address 0x%02x", reg)
+	}
+	m.completeOneShot()
+	if m.registers[maxspi.MAX31856_CR0_REG]&maxspi.MAX31856_CR0_AUTOCONVERT != 0 && reg >= maxspi.MAX31856_CJTH_REG {
+		m.convert()
+	}
+	for i := range data {
+		data[i] = m.registers[(int(reg)+i)%len(m.registers)]
+	}
+	return nil
+}
+
+func (m *MAX31856) WriteByteData(reg uint8, val uint8) error {
+	return m.WriteBlockData(reg, []byte{val})
+}
+
+func (m *MAX31856) WriteBlockData(reg uint8, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.writes = append(m.writes, Write{Register: reg, Data: append([]byte(nil), data...)})
+	if reg&0x80 == 0 {
+		return fmt.Errorf("write to read address 0x%02x", reg)
+	}
+	for i, v := range data {
+		address := (int(reg&0x7F) + i) % len(m.registers)
+		//the conversion results and the status are read only
+		if address >= int(maxspi.MAX31856_LTCBH_REG) {
+			continue
+		}
+		m.registers[address] = v
+	}
+	if m.registers[maxspi.MAX31856_CR0_REG]&maxspi.MAX31856_CR0_1SHOT != 0 && m.pendingUntil.IsZero() {
+		m.pendingUntil = time.Now().Add(m.conversionTime)
+		m.completeOneShot()
+	}
+	return nil
+}
+
+// completeOneShot does the pending one-shot conversion if its time has come, clearing the one-shot bit
+func (m *MAX31856) completeOneShot() {
+	if m.pendingUntil.IsZero() || time.Now().Before(m.pendingUntil) {
+		return
+	}
+	m.convert()
+	m.registers[maxspi.MAX31856_CR0_REG] &^= maxspi.MAX31856_CR0_1SHOT
+	m.pendingUntil = time.Time{}
+}
+
+func (m *MAX31856) WriteByte(val byte) error {
+	return m.WriteBytes([]byte{val})
+}
+
+func (m *MAX31856) WriteBytes(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return m.WriteBlockData(data[0], data[1:])
+}
+
+func (m *MAX31856) ReadCommandData(command []byte, data []byte) error {
+	return fmt.Errorf("not supported by the MAX31856")
+}
+
+func (m *MAX31856) Close() error {
+	return nil
+}
+
+// convert writes the simulated temperatures in the result registers and updates the fault status
+func (m *MAX31856) convert() {
+	m.conversions++
+	tc := uint32(int32(math.Round(m.temperature*128))) << 5
+	m.registers[maxspi.MAX31856_LTCBH_REG] = byte(tc >> 16)
+	m.registers[maxspi.MAX31856_LTCBM_REG] = byte(tc >> 8)
+	m.registers[maxspi.MAX31856_LTCBL_REG] = byte(tc)
+	cj := uint16(int16(math.Round(m.coldJunction*64))) << 2
+	m.registers[maxspi.MAX31856_CJTH_REG] = byte(cj >> 8)
+	m.registers[maxspi.MAX31856_CJTL_REG] = byte(cj)
+
+	var status uint8
+	high := float64(int16(uint16(m.registers[maxspi.MAX31856_LTHFTH_REG])<<8|uint16(m.registers[maxspi.MAX31856_LTHFTL_REG]))) / 16
+	low := float64(int16(uint16(m.registers[maxspi.MAX31856_LTLFTH_REG])<<8|uint16(m.registers[maxspi.MAX31856_LTLFTL_REG]))) / 16
+	if m.temperature > high {
+		status |= maxspi.MAX31856_FAULT_TCHIGH
+	}
+	if m.temperature < low {
+		status |= maxspi.MAX31856_FAULT_TCLOW
+	}
+	if m.coldJunction > float64(int8(m.registers[maxspi.MAX31856_CJHF_REG])) {
+		status |= maxspi.MAX31856_FAULT_CJHIGH
+	}
+	if m.coldJunction < float64(int8(m.registers[maxspi.MAX31856_CJLF_REG])) {
+		status |= maxspi.MAX31856_FAULT_CJLOW
+	}
+	if m.open {
+		status |= maxspi.MAX31856_FAULT_OPEN
+	}
+	m.registers[maxspi.MAX31856_SR_REG] = status
+}
@@ -0,0 +1,97 @@
+package spifake
+
+import (
+	"testing"
+
+	maxspi "github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi"
+)
+
+func TestMAX31856Registers(t *testing.T) {
+	tests := []struct {
+		name    string
+		reg     uint8
+		val     byte
+		want    byte
+		wantErr bool
+	}{
+		{name: "write with the flag", reg: maxspi.MAX31856_CR1_REG | 0x80, val: 0x24, want: 0x24},
+		{name: "write without the flag", reg: maxspi.MAX31856_CR1_REG, val: 0x24, want: 0x03, wantErr: true},
+		{name: "read only result", reg: maxspi.MAX31856_LTCBH_REG | 0x80, val: 0x55, want: 0x00},
+		{name: "read only status", reg: maxspi.MAX31856_SR_REG | 0x80, val: 0xFF, want: 0x00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMAX31856()
+			err := m.WriteByteData(tt.reg, tt.val)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("WriteByteData error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := m.Register(tt.reg); got != tt.want {
+				t.Errorf("register %#02x = %#02x, want %#02x", tt.reg&0x7F, got, tt.want)
+			}
+			if len(m.AllWrites()) != 1 {
+				t.Errorf("%d writes recorded, want 1", len(m.AllWrites()))
+			}
+		})
+	}
+	if _, err := NewMAX31856().ReadByteData(maxspi.MAX31856_CR0_REG | 0x80); err == nil {
+		t.Error("read from a write address should fail")
+	}
+}
+
+func TestMAX31856Conversion(t *testing.T) {
+	m := NewMAX31856()
+	m.SetTemperature(-1.5)
+	m.SetColdJunctionTemperature(20.25)
+	if m.Conversions() != 0 {
+		t.Fatal("conversion done before a request")
+	}
+	if err := m.WriteByteData(maxspi.MAX31856_CR0_REG|0x80, maxspi.MAX31856_CR0_1SHOT); err != nil {
+		t.Fatal(err)
+	}
+	if m.Register(maxspi.MAX31856_CR0_REG)&maxspi.MAX31856_CR0_1SHOT != 0 {
+		t.Error("one-shot bit not cleared at the end of the conversion")
+	}
+	data := make([]byte, 5)
+	if err := m.ReadBlockData(maxspi.MAX31856_CJTH_REG, data); err != nil {
+		t.Fatal(err)
+	}
+	//cold junction 20.25*64 << 2, thermocouple -1.5*128 << 5 on 24 bits
+	want := []byte{0x14, 0x40, 0xFF, 0xE8, 0x00}
+	for i := range want {
+		if data[i] != want[i] {
+			t.Fatalf("result registers = % x, want % x", data, want)
+		}
+	}
+
+	//while auto-converting each read of the results is a new conversion
+	m.WriteByteData(maxspi.MAX31856_CR0_REG|0x80, maxspi.MAX31856_CR0_AUTOCONVERT)
+	m.SetTemperature(100)
+	m.ReadBlockData(maxspi.MAX31856_LTCBH_REG, data[:3])
+	if data[0] != 0x06 || data[1] != 0x40 || data[2] != 0x00 {
+		t.Errorf("auto-conversion result = % x, want 06 40 00", data[:3])
+	}
+	if n := m.Conversions(); n != 2 {
+		t.Errorf("%d conversions, want 2", n)
+	}
+}
+
+func TestConnection(t *testing.T) {
+	c := NewConnection()
+	c.WriteBlockData(0x10, []byte{1, 2, 3})
+	got := make([]byte, 3)
+	if err := c.ReadBlockData(0x10, got); err != nil || got[0] != 1 || got[2] != 3 {
+		t.Errorf("ReadBlockData = % x, %v, want 01 02 03", got, err)
+	}
+	if w := c.AllWrites(); len(w) != 1 || w[0].Register != 0x10 {
+		t.Errorf("writes = %+v", w)
+	}
+	c.SetCommandResponse(0xAB, 0xCD)
+	if err := c.ReadCommandData(nil, got[:2]); err != nil || got[0] != 0xAB || got[1] != 0xCD {
+		t.Errorf("ReadCommandData = % x, %v", got[:2], err)
+	}
+	c.Close()
+	if _, err := c.ReadByteData(0x10); err == nil {
+		t.Error("read from a closed connection should fail")
+	}
+}
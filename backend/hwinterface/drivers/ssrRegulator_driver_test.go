@@ -0,0 +1,75 @@
+package drivers_test
+
+import (
+	"testing"
+
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers"
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/hwfake"
+)
+
+func TestSSRRegulatorSetPower(t *testing.T) {
+	tests := []byte{0, 1, 127, 255}
+	a := hwfake.NewAdaptor()
+	d := drivers.NewSSRRegulator(a, "37")
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for _, level := range tests {
+		a.Reset()
+		if err := d.SetPower(level); err != nil {
+			t.Fatalf("SetPower(%d): %v", level, err)
+		}
+		writes := a.PinWrites("37")
+		if len(writes) != 1 || !writes[0].Pwm || writes[0].Value != level {
+			t.Errorf("SetPower(%d) writes = %+v, want one PWM write", level, writes)
+		}
+	}
+}
+
+func TestSSRRegulatorOnOff(t *testing.T) {
+	tests := []struct {
+		name  string
+		do    func(d *drivers.SSRRegulatorDriver) error
+		value byte
+		state bool
+	}{
+		{"on", (*drivers.SSRRegulatorDriver).On, 1, true},
+		{"toggle off", (*drivers.SSRRegulatorDriver).Toggle, 0, false},
+		{"toggle on", (*drivers.SSRRegulatorDriver).Toggle, 1, true},
+		{"off", (*drivers.SSRRegulatorDriver).Off, 0, false},
+	}
+	a := hwfake.NewAdaptor()
+	d := drivers.NewSSRRegulator(a, "37")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.Reset()
+			if err := tt.do(d); err != nil {
+				t.Fatal(err)
+			}
+			writes := a.PinWrites("37")
+			if len(writes) != 1 || writes[0].Pwm || writes[0].Value != tt.value {
+				t.Errorf("writes = %+v, want one digital write of %d", writes, tt.value)
+			}
+			if d.State() != tt.state {
+				t.Errorf("State = %v, want %v", d.State(), tt.state)
+			}
+		})
+	}
+}
+
+func TestSSRRegulatorCommands(t *testing.T) {
+	a := hwfake.NewAdaptor()
+	d := drivers.NewSSRRegulator(a, "37")
+	if err := d.Command("Power")(map[string]interface{}{"level": 200.0}); err != nil {
+		t.Fatalf("Power command: %v", err)
+	}
+	if got := a.Pin("37"); got != 200 {
+		t.Errorf("pin after the Power command = %d, want 200", got)
+	}
+	if err := d.Command("Off")(nil); err != nil {
+		t.Fatalf("Off command: %v", err)
+	}
+	if got := a.Pin("37"); got != 0 {
+		t.Errorf("pin after the Off command = %d, want 0", got)
+	}
+}
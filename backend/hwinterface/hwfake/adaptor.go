@@ -0,0 +1,118 @@
+// Package hwfake contains an in-memory adaptor, to run the controller and the drivers without a raspberry.
+package hwfake
+
+import (
+	"sync"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi/spifake"
+)
+
+// PinWrite is a write done on a pin, digital or PWM
+type PinWrite struct {
+	Pin   string
+	Value byte
+	Pwm   bool
+	Time  time.Time
+}
+
+// Adaptor is a fake gobot adaptor with GPIO, PWM and SPI. It records the pin writes,
+// returns the input values set with SetInput and connects the simulated SPI devices of the embedded Connector.
+type Adaptor struct {
+	*spifake.Connector
+	mu     sync.Mutex
+	name   string
+	writes []PinWrite
+	pins   map[string]byte
+	inputs map[string]byte
+}
+
+// NewAdaptor returns an adaptor with all the pins at 0 and nothing on the SPI bus
+func NewAdaptor() *Adaptor {
+	return &Adaptor{
+		Connector: spifake.NewConnector(),
+		name:      "hwfake",
+		pins:      make(map[string]byte),
+		inputs:    make(map[string]byte),
+	}
+}
+
+// AttachMAX31856 connects a simulated MAX31856 to bus/chip and returns it
+func (a *Adaptor) AttachMAX31856(busNum, chip int) *spifake.MAX31856 {
+	device := spifake.NewMAX31856()
+	a.Attach(busNum, chip, device)
+	return device
+}
+
+// SetInput sets the value read from an input pin
+func (a *Adaptor) SetInput(pin string, value byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inputs[pin] = value
+}
+
+// Pin returns the last value written on a pin
+func (a *Adaptor) Pin(pin string) byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pins[pin]
+}
+
+// Writes returns a copy of the writes done on all the pins
+func (a *Adaptor) Writes() []PinWrite {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]PinWrite(nil), a.writes...)
+}
+
+// PinWrites returns the writes done on a pin
+func (a *Adaptor) PinWrites(pin string) []PinWrite {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	res := make([]PinWrite, 0)
+	for _, w := range a.writes {
+		if w.Pin == pin {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// Reset forgets the writes done, keeping the pin values
+func (a *Adaptor) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.writes = nil
+}
+
+func (a *Adaptor) DigitalWrite(pin string, val byte) error {
+	a.write(pin, val, false)
+	return nil
+}
+
+func (a *Adaptor) PwmWrite(pin string, val byte) error {
+	a.write(pin, val, true)
+	return nil
+}
+
+// DigitalRead returns the input set for the pin, or the last value written on it
+func (a *Adaptor) DigitalRead(pin string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if value, ok := a.inputs[pin]; ok {
+		return int(value), nil
+	}
+	return int(a.pins[pin]), nil
+}
+
+func (a *Adaptor) write(pin string, val byte, pwm bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pins[pin] = val
+	a.writes = append(a.writes, PinWrite{Pin: pin, Value: val, Pwm: pwm, Time: time.Now()})
+}
+
+func (a *Adaptor) Name() string        { return a.name }
+func (a *Adaptor) SetName(name string) { a.name = name }
+func (a *Adaptor) Connect() error      { return nil }
+func (a *Adaptor) Finalize() error     { return nil }
@@ -0,0 +1,67 @@
+package hwfake
+
+import "testing"
+
+func TestAdaptorPins(t *testing.T) {
+	a := NewAdaptor()
+	a.DigitalWrite("11", 1)
+	a.PwmWrite("37", 128)
+	a.DigitalWrite("11", 0)
+
+	if got := a.Pin("11"); got != 0 {
+		t.Errorf("Pin(11) = %d, want the last value written 0", got)
+	}
+	if got := a.Pin("37"); got != 128 {
+		t.Errorf("Pin(37) = %d, want 128", got)
+	}
+	if writes := a.PinWrites("11"); len(writes) != 2 || writes[0].Value != 1 || writes[0].Pwm {
+		t.Errorf("PinWrites(11) = %+v, want the two digital writes", writes)
+	}
+	if writes := a.PinWrites("37"); len(writes) != 1 || !writes[0].Pwm {
+		t.Errorf("PinWrites(37) = %+v, want one PWM write", writes)
+	}
+	if n := len(a.Writes()); n != 3 {
+		t.Errorf("%d writes, want 3", n)
+	}
+	a.Reset()
+	if n := len(a.Writes()); n != 0 {
+		t.Errorf("%d writes after Reset, want 0", n)
+	}
+	if got := a.Pin("37"); got != 128 {
+		t.Errorf("Pin(37) after Reset = %d, want 128 kept", got)
+	}
+}
+
+func TestAdaptorDigitalRead(t *testing.T) {
+	a := NewAdaptor()
+	if v, err := a.DigitalRead("29"); err != nil || v != 0 {
+		t.Errorf("DigitalRead of an unset pin = %d, %v, want 0", v, err)
+	}
+	a.DigitalWrite("29", 1)
+	if v, _ := a.DigitalRead("29"); v != 1 {
+		t.Errorf("DigitalRead = %d, want the value written 1", v)
+	}
+	a.SetInput("29", 0)
+	if v, _ := a.DigitalRead("29"); v != 0 {
+		t.Errorf("DigitalRead = %d, want the input set 0", v)
+	}
+}
+
+func TestAdaptorSpi(t *testing.T) {
+	a := NewAdaptor()
+	chip := a.AttachMAX31856(0, 1)
+	conn, err := a.GetSpiConnection(0, 1, 1, 8, 500000)
+	if err != nil {
+		t.Fatalf("GetSpiConnection: %v", err)
+	}
+	if conn != chip {
+		t.Error("GetSpiConnection on the chip select of the MAX31856 does not return it")
+	}
+	other, err := a.GetSpiConnection(0, 0, 1, 8, 500000)
+	if err != nil {
+		t.Fatalf("GetSpiConnection: %v", err)
+	}
+	if other != a.Connection(0, 0) {
+		t.Error("GetSpiConnection on a free chip select does not return the register connection")
+	}
+}
@@ -0,0 +1,142 @@
+package hwinterface
+
+import (
+	"sync"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+)
+
+const indicatorTick = 50 * time.Millisecond
+
+// blink is an output pattern: the output is on for the first part of each period.
+// A zero period means steady, on if on is not zero.
+type blink struct {
+	on, period time.Duration
+}
+
+var (
+	steadyOff = blink{}
+	steadyOn  = blink{on: 1}
+)
+
+func (b blink) active(elapsed time.Duration) bool {
+	if b.period == 0 {
+		return b.on > 0
+	}
+	return elapsed%b.period < b.on
+}
+
+// indicatorPattern is what the outputs do in a state
+type indicatorPattern struct {
+	ledOk, ledWorking, buzzer blink
+}
+
+// indicatorPatterns are made to be told apart from a distance: the working LED shows the program phase,
+// the ok LED blinks when the operator has something to do, the buzzer sounds until acknowledged
+var indicatorPatterns = map[commoninterface.IndicatorState]indicatorPattern{
+	commoninterface.IndicatorIdle:    {ledOk: steadyOn, ledWorking: steadyOff, buzzer: steadyOff},
+	commoninterface.IndicatorHeating: {ledOk: steadyOn, ledWorking: steadyOn, buzzer: steadyOff},
+	commoninterface.IndicatorHolding: {ledOk: steadyOn, ledWorking: blink{time.Second, 2 * time.Second}, buzzer: steadyOff},
+	commoninterface.IndicatorCooling: {ledOk: steadyOn, ledWorking: blink{200 * time.Millisecond, 2 * time.Second}, buzzer: steadyOff},
+	commoninterface.IndicatorPaused:  {ledOk: steadyOn, ledWorking: blink{250 * time.Millisecond, 500 * time.Millisecond}, buzzer: steadyOff},
+	commoninterface.IndicatorWaiting: {ledOk: blink{200 * time.Millisecond, 3 * time.Second}, ledWorking: steadyOff, buzzer: steadyOff},
+	commoninterface.IndicatorDiagnostics: {ledOk: blink{500 * time.Millisecond, time.Second},
+		ledWorking: blink{500 * time.Millisecond, time.Second}, buzzer: steadyOff},
+	commoninterface.IndicatorFinished: {ledOk: blink{time.Second, 2 * time.Second}, ledWorking: steadyOff, buzzer: blink{200 * time.Millisecond, 3 * time.Second}},
+	commoninterface.IndicatorFault: {ledOk: blink{100 * time.Millisecond, 200 * time.Millisecond},
+		ledWorking: blink{100 * time.Millisecond, 200 * time.Millisecond}, buzzer: blink{500 * time.Millisecond, time.Second}},
+}
+
+// onOffOutput is a LED or a buzzer
+type onOffOutput interface {
+	On() error
+	Off() error
+}
+
+// indicator drives the status LEDs and the optional buzzer with the pattern of the current state
+type indicator struct {
+	mu                        sync.Mutex
+	state                     commoninterface.IndicatorState
+	changed                   time.Time
+	ledOk, ledWorking, buzzer onOffOutput
+	outputs                   [3]bool
+	stop, done                chan struct{}
+}
+
+// newIndicator creates the indicator, buzzer can be nil
+func newIndicator(ledOk, ledWorking, buzzer onOffOutput) *indicator {
+	return &indicator{ledOk: ledOk, ledWorking: ledWorking, buzzer: buzzer, state: commoninterface.IndicatorIdle, changed: time.Now()}
+}
+
+func (i *indicator) Start() {
+	i.stop, i.done = make(chan struct{}), make(chan struct{})
+	i.refresh(time.Now(), true)
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(indicatorTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				i.refresh(now, false)
+			}
+		}
+	}(i.stop, i.done)
+}
+
+// Halt stops the patterns and switches off the outputs
+func (i *indicator) Halt() {
+	if i.stop != nil {
+		close(i.stop)
+		<-i.done
+		i.stop, i.done = nil, nil
+	}
+	i.ledOk.Off()
+	i.ledWorking.Off()
+	if i.buzzer != nil {
+		i.buzzer.Off()
+	}
+}
+
+func (i *indicator) SetState(state commoninterface.IndicatorState) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if state == i.state {
+		return
+	}
+	i.state = state
+	i.changed = time.Now()
+}
+
+func (i *indicator) State() commoninterface.IndicatorState {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.state
+}
+
+// refresh sets the outputs for the pattern at the time now, writing only the ones that changed unless force
+func (i *indicator) refresh(now time.Time, force bool) {
+	i.mu.Lock()
+	pattern := indicatorPatterns[i.state]
+	elapsed := now.Sub(i.changed)
+	i.mu.Unlock()
+	outputs := []onOffOutput{i.ledOk, i.ledWorking, i.buzzer}
+	for idx, b := range []blink{pattern.ledOk, pattern.ledWorking, pattern.buzzer} {
+		if outputs[idx] == nil {
+			continue
+		}
+		active := b.active(elapsed)
+		if active == i.outputs[idx] && !force {
+			continue
+		}
+		i.outputs[idx] = active
+		if active {
+			outputs[idx].On()
+		} else {
+			outputs[idx].Off()
+		}
+	}
+}
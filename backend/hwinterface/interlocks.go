@@ -0,0 +1,145 @@
+package hwinterface
+
+import (
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/config"
+	"gobot.io/x/gobot/v2/drivers/gpio"
+)
+
+const interlockPollTime = 10 * time.Millisecond
+
+// debouncedInput is a digital input that changes state only after the new value has been stable for the debounce time
+type debouncedInput struct {
+	pin       string
+	activeLow bool
+	debounce  time.Duration
+	active    bool
+	candidate bool
+	since     time.Time
+}
+
+func newDebouncedInput(pin string, activeLow bool, debounce time.Duration) *debouncedInput {
+	if pin == "" {
+		return nil
+	}
+	return &debouncedInput{pin: pin, activeLow: activeLow, debounce: debounce}
+}
+
+// read reads the pin, without debouncing
+func (i *debouncedInput) read(reader gpio.DigitalReader) (bool, error) {
+	value, err := reader.DigitalRead(i.pin)
+	if err != nil {
+		return false, err
+	}
+	return (value == 1) != i.activeLow, nil
+}
+
+// update reads the pin and returns true if the debounced state changed
+func (i *debouncedInput) update(reader gpio.DigitalReader, now time.Time) bool {
+	active, err := i.read(reader)
+	if err != nil {
+		return false
+	}
+	if active != i.candidate {
+		i.candidate = active
+		i.since = now
+	}
+	if i.candidate != i.active && now.Sub(i.since) >= i.debounce {
+		i.active = i.candidate
+		return true
+	}
+	return false
+}
+
+// startInterlocks starts polling the emergency stop and the lid switch, if configured
+func (d *piController) startInterlocks(reader gpio.DigitalReader, hardware config.HardwareConfig) {
+	debounce := time.Duration(hardware.InputDebounceMs) * time.Millisecond
+	eStop := newDebouncedInput(hardware.EmergencyStopPin, hardware.EmergencyStopActiveLow, debounce)
+	lid := newDebouncedInput(hardware.LidSwitchPin, hardware.LidSwitchActiveLow, debounce)
+	if eStop == nil && lid == nil {
+		return
+	}
+	//the state at startup is taken as it is, a pressed emergency stop must trip the oven immediately
+	for _, input := range []*debouncedInput{eStop, lid} {
+		if input == nil {
+			continue
+		}
+		if active, err := input.read(reader); err == nil {
+			input.active, input.candidate = active, active
+		} else {
+			d.logger.Error("Cannot read interlock input", "pin", input.pin, "err", err)
+		}
+	}
+	if eStop != nil {
+		d.setEmergencyStop(eStop.active)
+	}
+	if lid != nil {
+		d.setLidOpen(lid.active)
+	}
+
+	d.interlocksStop, d.interlocksDone = make(chan struct{}), make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interlockPollTime)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				if eStop != nil && eStop.update(reader, now) {
+					d.setEmergencyStop(eStop.active)
+				}
+				if lid != nil && lid.update(reader, now) {
+					d.setLidOpen(lid.active)
+				}
+			}
+		}
+	}(d.interlocksStop, d.interlocksDone)
+}
+
+// stopInterlocks stops the input polling
+func (d *piController) stopInterlocks() {
+	if d.interlocksStop == nil {
+		return
+	}
+	close(d.interlocksStop)
+	<-d.interlocksDone
+	d.interlocksStop, d.interlocksDone = nil, nil
+}
+
+// setEmergencyStop trips the oven when the emergency stop is pressed. The trip is reset only by a new program
+// started with the button released.
+func (d *piController) setEmergencyStop(pressed bool) {
+	d.interlocksMu.Lock()
+	d.interlocks.EmergencyStop = pressed
+	d.interlocksMu.Unlock()
+	if pressed {
+		d.trip("emergency stop pressed")
+	} else {
+		d.logger.Info("Emergency stop released")
+	}
+}
+
+// setLidOpen cuts the power while the lid is open, the program is paused by the worker
+func (d *piController) setLidOpen(open bool) {
+	d.interlocksMu.Lock()
+	d.interlocks.LidOpen = open
+	d.interlocksMu.Unlock()
+	if open {
+		d.logger.Info("Lid opened, cutting power")
+		d.actualPercentual = 0
+		d.ssrPowerController.SetPower(0)
+	} else {
+		d.logger.Info("Lid closed")
+	}
+}
+
+// GetInterlocks returns the state of the emergency stop and of the lid
+func (d *piController) GetInterlocks() commoninterface.InterlockState {
+	d.interlocksMu.RLock()
+	defer d.interlocksMu.RUnlock()
+	return d.interlocks
+}
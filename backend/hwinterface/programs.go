@@ -1,6 +1,11 @@
 package hwinterface
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+)
 
 func (c *piController) GetPercentual() float64 {
 	return c.actualPercentual
@@ -9,6 +14,16 @@ func (c *piController) GetMaxPower() float64 {
 	return c.maxPower
 }
 func (c *piController) SetPercentual(f float64) error {
+	if tripped, reason := c.tripState(); tripped {
+		c.actualPercentual = 0
+		c.ssrPowerController.SetPower(0)
+		return fmt.Errorf("%w: %s", commoninterface.ErrSafetyTrip, reason)
+	}
+	if c.GetInterlocks().LidOpen {
+		//the program is paused by the worker, nothing is heated with the lid open
+		c.actualPercentual = 0
+		return c.ssrPowerController.SetPower(0)
+	}
 	c.actualPercentual = f
 	var b [8]byte
 	binary.LittleEndian.PutUint64(b[:], uint64(f*255))
@@ -17,11 +32,10 @@ func (c *piController) SetPercentual(f float64) error {
 func (c *piController) InitStartProgram() error {
 	c.logger.Info("Init start program")
 	var err error
-	err = c.ledOvenWorking.On()
-	if err != nil {
-		c.ledOvenWorking.Off()
+	if err = c.resetTrip(); err != nil {
 		return err
 	}
+	c.indicator.SetState(commoninterface.IndicatorHeating)
 	err = c.ovenRelayPower.On()
 	if err != nil {
 		c.ovenRelayPower.Off()
@@ -32,12 +46,18 @@ func (c *piController) InitStartProgram() error {
 
 func (c *piController) EndProgram() error {
 	c.logger.Info("End program")
-	var err error
-	err = c.ledOvenWorking.Off()
-	if err != nil {
-		c.ovenRelayPower.Off()
-		return err
+	if !c.indicator.State().NeedsAcknowledge() {
+		c.indicator.SetState(commoninterface.IndicatorIdle)
 	}
 	return c.ovenRelayPower.Off()
 
 }
+
+// SetIndicatorState sets the state shown by the status LEDs and the buzzer
+func (c *piController) SetIndicatorState(state commoninterface.IndicatorState) {
+	c.indicator.SetState(state)
+}
+
+func (c *piController) GetIndicatorState() commoninterface.IndicatorState {
+	return c.indicator.State()
+}
@@ -0,0 +1,137 @@
+package hwinterface
+
+import (
+	"fmt"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/config"
+)
+
+// configureThresholds programs the hardware fault thresholds of the thermocouple reader,
+// that are a second limit independent from the software controller
+func (d *piController) configureThresholds(channel *sensorChannel, c config.SensorConfig) {
+	sensor, ok := channel.sensor.(thresholdSensor)
+	if !ok {
+		if c.MaxTemperature > 0 || c.ColdJunctionMaxTemperature > 0 {
+			d.logger.Error("Hardware thresholds not supported by the sensor", "sensor", channel.name, "type", c.Type)
+		}
+		return
+	}
+	var err error
+	if c.MaxTemperature > 0 {
+		low := c.MinTemperature
+		if low == 0 || low >= c.MaxTemperature {
+			low = -250
+		}
+		err = sensor.SetTemperatureThresholds(low, c.MaxTemperature)
+	} else {
+		err = sensor.ResetTemperatureThresholds()
+	}
+	if err != nil {
+		d.logger.Error("Cannot set thermocouple thresholds", "sensor", channel.name, "err", err)
+	}
+	if c.ColdJunctionMaxTemperature > 0 {
+		low := c.ColdJunctionMinTemperature
+		if low == 0 || low >= c.ColdJunctionMaxTemperature {
+			low = -64
+		}
+		err = sensor.SetColdJunctionThresholds(int8(low), int8(min(c.ColdJunctionMaxTemperature, 127)))
+	} else {
+		err = sensor.ResetColdJunctionThresholds()
+	}
+	if err != nil {
+		d.logger.Error("Cannot set cold junction thresholds", "sensor", channel.name, "err", err)
+	}
+
+	low, high, err := sensor.GetTemperatureThresholds()
+	if err != nil {
+		d.logger.Error("Cannot read thermocouple thresholds", "sensor", channel.name, "err", err)
+		return
+	}
+	cjLow, cjHigh, err := sensor.GetColdJunctionThresholds()
+	if err != nil {
+		d.logger.Error("Cannot read cold junction thresholds", "sensor", channel.name, "err", err)
+		return
+	}
+	d.logger.Info("Hardware thresholds", "sensor", channel.name, "low", low, "high", high, "coldJunctionLow", cjLow, "coldJunctionHigh", cjHigh)
+}
+
+// checkFaults reads the fault register of the thermocouple readers and trips the oven if a threshold has been crossed.
+// An open thermocouple is set as the sensor error.
+func (d *piController) checkFaults() error {
+	for _, s := range d.sensors {
+		sensor, ok := s.sensor.(thresholdSensor)
+		if !ok {
+			continue
+		}
+		fault, err := sensor.ReadFault()
+		if err != nil {
+			s.err = err
+			continue
+		}
+		if fault.ThresholdTripped() {
+			d.trip(fmt.Sprintf("%s threshold fault %+v", s.name, fault))
+		}
+		if fault.Open && s.err == nil {
+			s.err = fmt.Errorf("thermocouple open circuit")
+		}
+	}
+	if tripped, reason := d.tripState(); tripped {
+		return fmt.Errorf("%w: %s", commoninterface.ErrSafetyTrip, reason)
+	}
+	return nil
+}
+
+// trip cuts the power to the oven. It stays tripped until a new program starts with no faults present.
+func (d *piController) trip(reason string) {
+	d.safetyMu.Lock()
+	alreadyTripped := d.tripped
+	d.tripped = true
+	d.tripReason = reason
+	d.safetyMu.Unlock()
+	if alreadyTripped {
+		return
+	}
+	d.raiseAlarm("safety", "trip, cutting power: "+reason)
+	d.actualPercentual = 0
+	d.ssrPowerController.SetPower(0)
+	d.ovenRelayPower.Off()
+	d.indicator.SetState(commoninterface.IndicatorFault)
+}
+
+func (d *piController) tripState() (bool, string) {
+	d.safetyMu.Lock()
+	defer d.safetyMu.Unlock()
+	return d.tripped, d.tripReason
+}
+
+// resetTrip clears the trip if the thermocouple reader does not report threshold faults anymore
+func (d *piController) resetTrip() error {
+	if tripped, _ := d.tripState(); !tripped {
+		return nil
+	}
+	if d.GetInterlocks().EmergencyStop {
+		return fmt.Errorf("%w: emergency stop still pressed", commoninterface.ErrSafetyTrip)
+	}
+	d.sensorsMu.Lock()
+	defer d.sensorsMu.Unlock()
+	for _, s := range d.sensors {
+		sensor, ok := s.sensor.(thresholdSensor)
+		if !ok {
+			continue
+		}
+		fault, err := sensor.ReadFault()
+		if err != nil {
+			return err
+		}
+		if fault.ThresholdTripped() {
+			return fmt.Errorf("%w: %s threshold fault still present %+v", commoninterface.ErrSafetyTrip, s.name, fault)
+		}
+	}
+	d.safetyMu.Lock()
+	d.tripped = false
+	d.tripReason = ""
+	d.safetyMu.Unlock()
+	d.logger.Info("Safety trip reset")
+	return nil
+}
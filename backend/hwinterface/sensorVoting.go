@@ -0,0 +1,163 @@
+package hwinterface
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+)
+
+const (
+	VotingMean   = "mean"
+	VotingMax    = "max"
+	VotingMedian = "median"
+
+	maxAlarmsKept = 100
+)
+
+// voteTemperature combines the last readings of the sensors with the configured policy.
+// Sensors in fault, or too far from the others, are excluded and an alarm is raised.
+func (d *piController) voteTemperature() (float64, error) {
+	valid := make([]*sensorChannel, 0, len(d.sensors))
+	for _, s := range d.sensors {
+		if s.err != nil {
+			d.setSensorAlarm(s, true, fmt.Sprintf("sensor in fault: %v", s.err))
+			continue
+		}
+		valid = append(valid, s)
+	}
+	if len(valid) == 0 {
+		if len(d.sensors) == 1 {
+			return 0, d.sensors[0].err
+		}
+		return 0, fmt.Errorf("all the sensors are in fault")
+	}
+
+	if d.maxDeviation > 0 && len(valid) == 2 {
+		// with two sensors there is no way to know which one is wrong: the hotter one is used,
+		// so that the oven is never heated more than what the sensors say
+		disagree := math.Abs(valid[0].temperature-valid[1].temperature) > d.maxDeviation
+		d.setDisagreementAlarm(disagree, fmt.Sprintf("sensors %s and %s differ by more than %.1f degrees (%.1f, %.1f), using the higher one",
+			valid[0].name, valid[1].name, d.maxDeviation, valid[0].temperature, valid[1].temperature))
+		d.setSensorAlarm(valid[0], false, "")
+		d.setSensorAlarm(valid[1], false, "")
+		if disagree {
+			return max(valid[0].temperature, valid[1].temperature), nil
+		}
+	} else if d.maxDeviation > 0 && len(valid) > 2 {
+		reference := median(temperatures(valid))
+		agreeing := make([]*sensorChannel, 0, len(valid))
+		for _, s := range valid {
+			if math.Abs(s.temperature-reference) > d.maxDeviation {
+				d.setSensorAlarm(s, true, fmt.Sprintf("sensor reads %.1f, more than %.1f degrees from the others (%.1f)", s.temperature, d.maxDeviation, reference))
+				continue
+			}
+			d.setSensorAlarm(s, false, "")
+			agreeing = append(agreeing, s)
+		}
+		// with an even number of sensors split in two groups, no sensor is close to the median
+		d.setDisagreementAlarm(len(agreeing) == 0, fmt.Sprintf("no sensor is within %.1f degrees of the others (%v)",
+			d.maxDeviation, temperatures(valid)))
+		if len(agreeing) == 0 {
+			return 0, fmt.Errorf("the sensors disagree, no sensor is within %.1f degrees of the median %.1f", d.maxDeviation, reference)
+		}
+		valid = agreeing
+	} else {
+		for _, s := range valid {
+			d.setSensorAlarm(s, false, "")
+		}
+	}
+
+	values := temperatures(valid)
+	switch d.votingPolicy {
+	case VotingMax:
+		return slices.Max(values), nil
+	case VotingMedian:
+		return median(values), nil
+	default:
+		return mean(values), nil
+	}
+}
+
+// setSensorAlarm raises an alarm when a sensor goes in alarm, and logs when it comes back
+func (d *piController) setSensorAlarm(s *sensorChannel, inAlarm bool, message string) {
+	if inAlarm == s.inAlarm {
+		return
+	}
+	s.inAlarm = inAlarm
+	if inAlarm {
+		d.raiseAlarm(s.name, message)
+	} else {
+		d.logger.Info("Sensor back to normal", "sensor", s.name)
+	}
+}
+
+func (d *piController) setDisagreementAlarm(disagree bool, message string) {
+	if disagree == d.sensorsDisagree {
+		return
+	}
+	d.sensorsDisagree = disagree
+	if disagree {
+		d.raiseAlarm("sensors", message)
+	} else {
+		d.logger.Info("Sensors agree again")
+	}
+}
+
+// raiseAlarm logs the alarm and keeps it in the list returned by GetAlarms
+func (d *piController) raiseAlarm(source, message string) {
+	d.logger.Error("Alarm", "source", source, "message", message)
+	alarm := commoninterface.Alarm{Time: time.Now(), Source: source, Message: message}
+	d.alarmsMu.Lock()
+	d.alarms = append(d.alarms, alarm)
+	if len(d.alarms) > maxAlarmsKept {
+		d.alarms = d.alarms[len(d.alarms)-maxAlarmsKept:]
+	}
+	handler := d.alarmHandler
+	d.alarmsMu.Unlock()
+	if handler != nil {
+		handler(alarm)
+	}
+}
+
+// SetAlarmHandler sets a function called at each alarm raised
+func (d *piController) SetAlarmHandler(handler func(commoninterface.Alarm)) {
+	d.alarmsMu.Lock()
+	defer d.alarmsMu.Unlock()
+	d.alarmHandler = handler
+}
+
+// GetAlarms returns the last alarms raised
+func (d *piController) GetAlarms() []commoninterface.Alarm {
+	d.alarmsMu.Lock()
+	defer d.alarmsMu.Unlock()
+	return slices.Clone(d.alarms)
+}
+
+func temperatures(sensors []*sensorChannel) []float64 {
+	values := make([]float64, len(sensors))
+	for idx, s := range sensors {
+		values[idx] = s.temperature
+	}
+	return values
+}
+
+func mean(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
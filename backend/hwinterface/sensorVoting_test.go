@@ -0,0 +1,77 @@
+package hwinterface
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+)
+
+func newVotingController(policy string, maxDeviation float64, readings ...float64) *piController {
+	d := &piController{
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		votingPolicy: policy,
+		maxDeviation: maxDeviation,
+	}
+	for _, r := range readings {
+		d.sensors = append(d.sensors, &sensorChannel{name: "sensor", temperature: r})
+	}
+	return d
+}
+
+func TestVoteTemperature(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		maxDeviation float64
+		readings     []float64
+		want         float64
+		wantErr      bool
+		wantAlarms   int
+	}{
+		{name: "single", policy: VotingMean, readings: []float64{500}, want: 500},
+		{name: "mean", policy: VotingMean, readings: []float64{500, 510, 520}, want: 510},
+		{name: "max", policy: VotingMax, readings: []float64{500, 510, 520}, want: 520},
+		{name: "median of even", policy: VotingMedian, readings: []float64{500, 510, 520, 530}, want: 515},
+		{name: "two agreeing", policy: VotingMean, maxDeviation: 10, readings: []float64{500, 505}, want: 502.5},
+		{name: "two disagreeing use the higher", policy: VotingMean, maxDeviation: 10, readings: []float64{500, 600},
+			want: 600, wantAlarms: 1},
+		{name: "outlier excluded", policy: VotingMean, maxDeviation: 10, readings: []float64{500, 502, 504, 900},
+			want: 502, wantAlarms: 1},
+		{name: "two groups with max", policy: VotingMax, maxDeviation: 10, readings: []float64{0, 0, 100, 100},
+			wantErr: true, wantAlarms: 5},
+		{name: "two groups with mean", policy: VotingMean, maxDeviation: 10, readings: []float64{0, 0, 100, 100},
+			wantErr: true, wantAlarms: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newVotingController(tt.policy, tt.maxDeviation, tt.readings...)
+			got, err := d.voteTemperature()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("voteTemperature = %v, %v, want error %v", got, err, tt.wantErr)
+			}
+			if math.IsNaN(got) {
+				t.Fatal("voteTemperature returned NaN")
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("voteTemperature = %v, want %v", got, tt.want)
+			}
+			if n := len(d.GetAlarms()); n != tt.wantAlarms {
+				t.Errorf("%d alarms, want %d: %v", n, tt.wantAlarms, d.GetAlarms())
+			}
+		})
+	}
+}
+
+func TestVoteTemperatureFaults(t *testing.T) {
+	d := newVotingController(VotingMean, 0, 500, 520)
+	d.sensors[0].err = errors.New("open")
+	if got, err := d.voteTemperature(); err != nil || got != 520 {
+		t.Errorf("voteTemperature = %v, %v, want 520 from the sensor not in fault", got, err)
+	}
+	d.sensors[1].err = errors.New("open")
+	if _, err := d.voteTemperature(); err == nil {
+		t.Error("voteTemperature with all the sensors in fault should fail")
+	}
+}
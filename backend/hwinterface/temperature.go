@@ -1,7 +1,26 @@
 package hwinterface
 
-func (c piController) GetTemperature() (float64, error) {
-	value, err := c.temperatureReader.GetTemperature()
+import (
+	"errors"
+
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi"
+)
+
+// GetTemperature reads all the sensors and returns the temperature given by the voting policy
+func (c *piController) GetTemperature() (float64, error) {
+	c.sensorsMu.Lock()
+	defer c.sensorsMu.Unlock()
+	for _, s := range c.sensors {
+		s.temperature, s.err = s.sensor.GetTemperature()
+		if s.err != nil {
+			c.logger.Error("Sensor read error", "sensor", s.name, "err", s.err)
+		}
+	}
+	if err := c.checkFaults(); err != nil {
+		c.logger.Error("Error: %v", err)
+		return 0, err
+	}
+	value, err := c.voteTemperature()
 	if err != nil {
 		c.logger.Error("Error: %v", err)
 		return 0, err
@@ -9,3 +28,39 @@ func (c piController) GetTemperature() (float64, error) {
 
 	return value, nil
 }
+
+// GetSensorTemperatures returns the last reading of each sensor, 0 if the sensor is in fault
+func (c *piController) GetSensorTemperatures() []float64 {
+	c.sensorsMu.Lock()
+	defer c.sensorsMu.Unlock()
+	values := make([]float64, len(c.sensors))
+	for idx, s := range c.sensors {
+		if s.err == nil {
+			values[idx] = s.temperature
+		}
+	}
+	return values
+}
+
+// GetColdJunctionTemperature returns the cold junction temperature of the first sensor that can read it,
+// spi.ErrNotSupported if no sensor can
+func (c *piController) GetColdJunctionTemperature() (float64, error) {
+	c.sensorsMu.Lock()
+	defer c.sensorsMu.Unlock()
+	err := spi.ErrNotSupported
+	for _, s := range c.sensors {
+		value, sensorErr := s.sensor.GetColdJunctionTemperature()
+		if errors.Is(sensorErr, spi.ErrNotSupported) {
+			//not all the sensors have a cold junction reading, another one is tried
+			continue
+		}
+		if sensorErr == nil {
+			return value, nil
+		}
+		err = sensorErr
+	}
+	if !errors.Is(err, spi.ErrNotSupported) {
+		c.logger.Error("Error: %v", err)
+	}
+	return 0, err
+}
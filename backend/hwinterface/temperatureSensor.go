@@ -0,0 +1,112 @@
+package hwinterface
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/hwinterface/drivers/spi"
+	gobotspi "gobot.io/x/gobot/v2/drivers/spi"
+)
+
+const (
+	SensorMAX31856 = "max31856"
+	SensorMAX31855 = "max31855"
+	SensorMAX6675  = "max6675"
+)
+
+// TemperatureSensor is a thermocouple reader connected to the controller
+type TemperatureSensor interface {
+	Start() error
+	Halt() error
+	SetLogger(logger commoninterface.Logger)
+	GetTemperature() (float64, error)
+	GetColdJunctionTemperature() (float64, error)
+}
+
+// samplingSensor is a sensor that can convert continuously in background
+type samplingSensor interface {
+	StartAutoConversion(interval time.Duration, bufferSize int) error
+	StopAutoConversion() error
+}
+
+// thresholdSensor is a sensor with hardware fault thresholds
+type thresholdSensor interface {
+	SetTemperatureThresholds(low, high float64) error
+	ResetTemperatureThresholds() error
+	GetTemperatureThresholds() (low, high float64, err error)
+	SetColdJunctionThresholds(low, high int8) error
+	ResetColdJunctionThresholds() error
+	GetColdJunctionThresholds() (low, high int8, err error)
+	ReadFault() (spi.FaultState, error)
+}
+
+// sensorChannel is a sensor connected to the controller, with its last reading
+type sensorChannel struct {
+	name        string
+	sensor      TemperatureSensor
+	config      config.SensorConfig
+	temperature float64
+	err         error
+	inAlarm     bool
+}
+
+// newSensorChannels creates a channel for each sensor in the configuration, or a default MAX31856 if none is configured
+func newSensorChannels(a gobotspi.Connector, c config.Config) ([]*sensorChannel, error) {
+	sensorConfigs := c.Sensors
+	if len(sensorConfigs) == 0 {
+		sensorConfigs = []config.SensorConfig{{}}
+	}
+	channels := make([]*sensorChannel, 0, len(sensorConfigs))
+	chipSelects := make(map[[2]int]string)
+	for idx, sensorConfig := range sensorConfigs {
+		name := sensorConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("sensor %d", idx+1)
+		}
+		chipSelect := [2]int{sensorConfig.BusNumber, sensorConfig.ChipNumber}
+		if other, ok := chipSelects[chipSelect]; ok {
+			return nil, fmt.Errorf("sensors %s and %s use the same bus %d and chip %d", other, name, sensorConfig.BusNumber, sensorConfig.ChipNumber)
+		}
+		chipSelects[chipSelect] = name
+		sensor, err := newTemperatureSensor(a, sensorConfig)
+		if err != nil {
+			return nil, fmt.Errorf("sensor %s: %w", name, err)
+		}
+		channels = append(channels, &sensorChannel{name: name, sensor: sensor, config: sensorConfig})
+	}
+	return channels, nil
+}
+
+// newTemperatureSensor creates the sensor driver selected in the configuration
+func newTemperatureSensor(a gobotspi.Connector, c config.SensorConfig) (TemperatureSensor, error) {
+	spiOptions := []func(gobotspi.Config){gobotspi.WithBusNumber(c.BusNumber), gobotspi.WithChipNumber(c.ChipNumber)}
+	switch strings.ToLower(c.Type) {
+	case "", SensorMAX31856:
+		thermocoupleType := spi.N
+		if c.ThermocoupleType != "" {
+			var err error
+			if thermocoupleType, err = spi.ParseThermocoupleType(c.ThermocoupleType); err != nil {
+				return nil, err
+			}
+		}
+		averageSamples := c.AverageSamples
+		if averageSamples == 0 {
+			averageSamples = 4
+		}
+		noiseRejection := c.NoiseRejection
+		if noiseRejection == 0 {
+			noiseRejection = 50
+		}
+		return spi.NewMAX31856Driver(a, spi.WithSpiConfig(spiOptions...), spi.WithAverageSample(averageSamples),
+			spi.WithNoiseRejection(noiseRejection), spi.WithThermocoupleType(thermocoupleType)), nil
+	case SensorMAX31855:
+		return spi.NewMAX31855Driver(a, spiOptions...), nil
+	case SensorMAX6675:
+		return spi.NewMAX6675Driver(a, spiOptions...), nil
+	default:
+		return nil, fmt.Errorf("unknown sensor type %s", c.Type)
+	}
+}
@@ -0,0 +1,121 @@
+package ovenprograms
+
+import (
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/eventlog"
+)
+
+// CooldownSegment is the segment name of the points recorded after the end of the program
+const CooldownSegment = "cooldown"
+
+// cooldownMaxReadErrors are the consecutive read errors that stop the cooldown recording
+const cooldownMaxReadErrors = 5
+
+// CooldownSummary is how the cooldown recording went
+type CooldownSummary struct {
+	Start          time.Time  `json:"start"`
+	End            *time.Time `json:"end,omitempty"`
+	EndTemperature float64    `json:"end-temperature"`
+	//Reason is why the recording stopped: cold, max-time, new-program, read-errors
+	Reason string `json:"reason,omitempty"`
+	//Points are the data points recorded while cooling, after the run summary
+	Points int `json:"points"`
+}
+
+// IsCoolingDown returns true while the cooling after a program is recorded
+func (d *OvenProgramWorker) IsCoolingDown() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.cooldownStop != nil
+}
+
+// startCooldown keeps recording the run at a lower rate after the end of the program, with the power off,
+// until the oven is cold or the maximum time passes. A new program stops it.
+func (d *OvenProgramWorker) startCooldown(runName, programName string, cooldown config.CooldownConfig) {
+	if !cooldown.Enabled || cooldown.SampleSeconds <= 0 || runName == "" {
+		return
+	}
+	temperature, err := d.oven.GetTemperature()
+	if err != nil || temperature < cooldown.StopTemperature {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	d.mu.Lock()
+	if d.isWorking || d.cooldownStop != nil {
+		d.mu.Unlock()
+		return
+	}
+	d.cooldownStop, d.cooldownDone = stop, done
+	d.mu.Unlock()
+	d.publishState()
+	start := time.Now()
+	d.logRunEvent(runName, eventlog.Info, eventlog.CategoryProgram, "cooldown recording started", map[string]any{"temperature": temperature})
+	d.updateCooldownMetadata(runName, CooldownSummary{Start: start, EndTemperature: temperature})
+
+	go func() {
+		defer close(done)
+		summary := CooldownSummary{Start: start, EndTemperature: temperature}
+		deadline := start.Add(time.Duration(cooldown.MaxHours * float64(time.Hour)))
+		ticker := time.NewTicker(time.Duration(cooldown.SampleSeconds * float64(time.Second)))
+		defer ticker.Stop()
+		lastNow := start
+		readErrors := 0
+		for summary.Reason == "" {
+			select {
+			case <-stop:
+				summary.Reason = "new-program"
+				continue
+			case now := <-ticker.C:
+				d.oven.SetPercentual(0)
+				d.timeSeconds += now.Sub(lastNow).Seconds()
+				lastNow = now
+				raw, err := d.oven.GetTemperature()
+				if err != nil {
+					if readErrors++; readErrors >= cooldownMaxReadErrors {
+						summary.Reason = "read-errors"
+					}
+					continue
+				}
+				readErrors = 0
+				coldJunction, _ := d.oven.GetColdJunctionTemperature()
+				//there is no target while cooling, it is the temperature read
+				d.addDataPoint(createDataPoint(programName, CooldownSegment, d.timeSeconds, raw, raw, raw, 0, false, coldJunction, d.oven.GetSensorTemperatures()))
+				d.Save()
+				summary.Points++
+				summary.EndTemperature = raw
+				if raw < cooldown.StopTemperature {
+					summary.Reason = "cold"
+				} else if now.After(deadline) {
+					summary.Reason = "max-time"
+				}
+			}
+		}
+		d.Save()
+		end := time.Now()
+		summary.End = &end
+		d.updateCooldownMetadata(runName, summary)
+		d.logRunEvent(runName, eventlog.Info, eventlog.CategoryProgram, "cooldown recording ended", map[string]any{
+			"reason": summary.Reason, "temperature": summary.EndTemperature})
+		d.mu.Lock()
+		if d.cooldownStop == stop {
+			d.cooldownStop, d.cooldownDone = nil, nil
+		}
+		d.mu.Unlock()
+		d.publishState()
+	}()
+}
+
+// stopCooldown stops the cooldown recording, if running, and waits for it to end
+func (d *OvenProgramWorker) stopCooldown() {
+	d.mu.Lock()
+	stop, done := d.cooldownStop, d.cooldownDone
+	d.cooldownStop, d.cooldownDone = nil, nil
+	d.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
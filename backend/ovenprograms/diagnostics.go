@@ -0,0 +1,183 @@
+package ovenprograms
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/config"
+)
+
+// OutputTester is an oven that can check its outputs (relays, air) toggling them
+type OutputTester interface {
+	TestOutputs() []commoninterface.DiagnosticCheck
+}
+
+// RunDiagnostics does the self test and keeps the report, that gates the program start.
+// The power pulse is done only if requested and configured.
+func (d *OvenProgramWorker) RunDiagnostics(powerPulse bool) (commoninterface.DiagnosticsReport, error) {
+	d.mu.Lock()
+	if d.isWorking {
+		d.mu.Unlock()
+		return commoninterface.DiagnosticsReport{}, fmt.Errorf("cannot run diagnostics while working")
+	}
+	d.isWorking = true
+	d.applyNextConfig()
+	d.mu.Unlock()
+	//the cooldown recording turns the power off at each sample and writes in the previous run
+	d.stopCooldown()
+	defer func() {
+		d.mu.Lock()
+		d.isWorking = false
+		d.applyNextOvenConfig()
+		d.mu.Unlock()
+	}()
+	if d.logger != nil {
+		d.logger.Info("OvenProgramWorker: running diagnostics", "powerPulse", powerPulse)
+	}
+
+	previousIndicator := d.oven.GetIndicatorState()
+	d.setIndicator(commoninterface.IndicatorDiagnostics)
+
+	report := commoninterface.DiagnosticsReport{Time: time.Now()}
+	temperature, err := d.oven.GetTemperature()
+	thermocoupleOk := err == nil
+	if err != nil {
+		report.Checks = append(report.Checks, commoninterface.DiagnosticCheck{Name: "thermocouple", Status: commoninterface.DiagnosticFail, Message: err.Error()})
+	} else {
+		report.Checks = append(report.Checks, commoninterface.DiagnosticCheck{Name: "thermocouple", Status: commoninterface.DiagnosticPass, Message: fmt.Sprintf("reading %.1f", temperature)})
+	}
+	report.Checks = append(report.Checks, d.checkColdJunction(temperature, thermocoupleOk))
+	if tester, ok := d.oven.(OutputTester); ok {
+		report.Checks = append(report.Checks, tester.TestOutputs()...)
+	} else {
+		report.Checks = append(report.Checks, commoninterface.DiagnosticCheck{Name: "outputs", Status: commoninterface.DiagnosticSkipped, Message: "not supported by the oven"})
+	}
+	if powerPulse && d.diagnosticsConfig.PowerPulseSeconds > 0 && thermocoupleOk {
+		report.Checks = append(report.Checks, d.checkPowerPulse())
+		//the pulse shows the heating state while the power is on
+		d.setIndicator(commoninterface.IndicatorDiagnostics)
+	} else {
+		report.Checks = append(report.Checks, commoninterface.DiagnosticCheck{Name: "power pulse", Status: commoninterface.DiagnosticSkipped})
+	}
+
+	report.Passed = true
+	for _, c := range report.Checks {
+		if c.Status == commoninterface.DiagnosticFail {
+			report.Passed = false
+		}
+	}
+	d.mu.Lock()
+	d.diagnostics = &report
+	d.mu.Unlock()
+	switch {
+	case !report.Passed:
+		//a failure blocks the programs, it is signaled until acknowledged
+		d.setIndicator(commoninterface.IndicatorFault)
+	case previousIndicator.NeedsAcknowledge():
+		d.setIndicator(previousIndicator)
+	default:
+		d.setIndicator(commoninterface.IndicatorIdle)
+	}
+	if d.logger != nil {
+		d.logger.Info("OvenProgramWorker: diagnostics done", "passed", report.Passed)
+	}
+	return report, nil
+}
+
+// GetDiagnostics returns the last diagnostics report, nil if never run
+func (d *OvenProgramWorker) GetDiagnostics() *commoninterface.DiagnosticsReport {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.diagnostics
+}
+
+// DiagnosticsFailed returns true if the last diagnostics found a failure
+func (d *OvenProgramWorker) DiagnosticsFailed() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.diagnostics != nil && !d.diagnostics.Passed
+}
+
+// checkColdJunction checks that the thermocouple reads about the cold junction temperature with the kiln cold
+func (d *OvenProgramWorker) checkColdJunction(temperature float64, thermocoupleOk bool) commoninterface.DiagnosticCheck {
+	check := commoninterface.DiagnosticCheck{Name: "cold junction plausibility"}
+	coldJunction, err := d.oven.GetColdJunctionTemperature()
+	switch {
+	case errors.Is(err, commoninterface.ErrNotSupported):
+		check.Status, check.Message = commoninterface.DiagnosticSkipped, "the sensors have no cold junction reading"
+	case err != nil:
+		check.Status, check.Message = commoninterface.DiagnosticWarn, "cannot read the cold junction: "+err.Error()
+	case !thermocoupleOk:
+		check.Status, check.Message = commoninterface.DiagnosticSkipped, "no thermocouple reading"
+	case temperature > d.diagnosticsConfig.ColdKilnTemperature:
+		check.Status, check.Message = commoninterface.DiagnosticSkipped, fmt.Sprintf("kiln not cold (%.1f)", temperature)
+	case math.Abs(temperature-coldJunction) > d.diagnosticsConfig.MaxColdJunctionDifference:
+		check.Status = commoninterface.DiagnosticFail
+		check.Message = fmt.Sprintf("thermocouple %.1f and cold junction %.1f differ by more than %.1f", temperature, coldJunction, d.diagnosticsConfig.MaxColdJunctionDifference)
+	default:
+		check.Status, check.Message = commoninterface.DiagnosticPass, fmt.Sprintf("thermocouple %.1f, cold junction %.1f", temperature, coldJunction)
+	}
+	return check
+}
+
+// checkPowerPulse gives full power for the configured time and checks the temperature rise,
+// waiting as long again after the pulse as the heat takes time to reach the thermocouple
+func (d *OvenProgramWorker) checkPowerPulse() commoninterface.DiagnosticCheck {
+	check := commoninterface.DiagnosticCheck{Name: "power pulse"}
+	start, err := d.oven.GetTemperature()
+	if err != nil {
+		check.Status, check.Message = commoninterface.DiagnosticFail, err.Error()
+		return check
+	}
+	if err := d.oven.InitStartProgram(); err != nil {
+		check.Status, check.Message = commoninterface.DiagnosticFail, err.Error()
+		return check
+	}
+	defer func() {
+		d.oven.SetPercentual(0)
+		d.oven.EndProgram()
+	}()
+	if err := d.oven.SetPercentual(1); err != nil {
+		check.Status, check.Message = commoninterface.DiagnosticFail, err.Error()
+		return check
+	}
+	pulse := time.Duration(d.diagnosticsConfig.PowerPulseSeconds * float64(time.Second))
+	time.Sleep(pulse)
+	if err := d.oven.SetPercentual(0); err != nil {
+		check.Status, check.Message = commoninterface.DiagnosticFail, err.Error()
+		return check
+	}
+	maxTemperature := start
+	deadline := time.Now().Add(pulse)
+	for time.Now().Before(deadline) {
+		t, err := d.oven.GetTemperature()
+		if err != nil {
+			check.Status, check.Message = commoninterface.DiagnosticFail, err.Error()
+			return check
+		}
+		maxTemperature = max(maxTemperature, t)
+		time.Sleep(time.Second)
+	}
+	rise := maxTemperature - start
+	if rise < d.diagnosticsConfig.PowerPulseMinRise {
+		check.Status = commoninterface.DiagnosticFail
+		check.Message = fmt.Sprintf("temperature rise %.1f, expected at least %.1f", rise, d.diagnosticsConfig.PowerPulseMinRise)
+	} else {
+		check.Status, check.Message = commoninterface.DiagnosticPass, fmt.Sprintf("temperature rise %.1f", rise)
+	}
+	return check
+}
+
+func newDiagnosticsConfig(c config.Config) config.DiagnosticsConfig {
+	diagnostics := c.Diagnostics
+	if diagnostics.MaxColdJunctionDifference <= 0 {
+		diagnostics.MaxColdJunctionDifference = config.DefaultDiagnosticsConfig().MaxColdJunctionDifference
+	}
+	if diagnostics.ColdKilnTemperature <= 0 {
+		diagnostics.ColdKilnTemperature = config.DefaultDiagnosticsConfig().ColdKilnTemperature
+	}
+	return diagnostics
+}
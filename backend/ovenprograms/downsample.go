@@ -0,0 +1,64 @@
+package ovenprograms
+
+import "math"
+
+// Downsample returns at most maxPoints points chosen with the largest triangle three buckets algorithm on the
+// oven temperature, that keeps the shape of the curve: peaks and steps are not lost as taking every nth point.
+// The first and the last points are always kept. With maxPoints 0 or more than the points, the array is returned as it is.
+func (history ProgramDataPointArray) Downsample(maxPoints int) ProgramDataPointArray {
+	n := len(history)
+	if maxPoints <= 0 || n <= maxPoints {
+		return history
+	}
+	if maxPoints == 1 {
+		return history[n-1:]
+	}
+	if maxPoints == 2 {
+		return ProgramDataPointArray{history[0], history[n-1]}
+	}
+	x := func(i int) float64 { return history[i].SecondsFromStart }
+	y := func(i int) float64 { return history[i].OvenTemperature }
+	res := make(ProgramDataPointArray, 0, maxPoints)
+	res = append(res, history[0])
+	//the points between the first and the last are divided in maxPoints-2 buckets, one point is taken from each
+	bucketSize := float64(n-2) / float64(maxPoints-2)
+	selected := 0
+	for bucket := 0; bucket < maxPoints-2; bucket++ {
+		start := int(float64(bucket)*bucketSize) + 1
+		end := int(float64(bucket+1)*bucketSize) + 1
+		//the third vertex is the average of the next bucket, or the last point
+		nextStart, nextEnd := end, min(int(float64(bucket+2)*bucketSize)+1, n-1)
+		if nextStart >= nextEnd {
+			nextStart, nextEnd = n-1, n
+		}
+		var averageX, averageY float64
+		for i := nextStart; i < nextEnd; i++ {
+			averageX += x(i)
+			averageY += y(i)
+		}
+		averageX /= float64(nextEnd - nextStart)
+		averageY /= float64(nextEnd - nextStart)
+
+		maxArea, chosen := -1.0, start
+		for i := start; i < end; i++ {
+			area := math.Abs((x(selected)-averageX)*(y(i)-y(selected)) - (x(selected)-x(i))*(averageY-y(selected)))
+			if area > maxArea {
+				maxArea, chosen = area, i
+			}
+		}
+		res = append(res, history[chosen])
+		selected = chosen
+	}
+	return append(res, history[n-1])
+}
+
+// Between returns the points with the seconds from start in [from, to], to 0 means up to the end
+func (history ProgramDataPointArray) Between(from, to float64) ProgramDataPointArray {
+	res := make(ProgramDataPointArray, 0)
+	for _, p := range history {
+		if p.SecondsFromStart >= from && (to <= 0 || p.SecondsFromStart <= to) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
@@ -2,6 +2,7 @@ package ovenprograms
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -11,10 +12,15 @@ import (
 
 	"github.com/idalmasso/ovencontrol/backend/commoninterface"
 	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/eventlog"
+	"github.com/idalmasso/ovencontrol/backend/events"
+	"github.com/idalmasso/ovencontrol/backend/runfile"
 )
 
 type Oven interface {
 	GetTemperature() (float64, error)
+	GetColdJunctionTemperature() (float64, error)
+	GetSensorTemperatures() []float64
 	GetPercentual() float64
 	GetMaxPower() float64
 	SetPercentual(float64) error
@@ -22,6 +28,15 @@ type Oven interface {
 	EndProgram() error
 	OpenAir() error
 	CloseAir() error
+	GetInterlocks() commoninterface.InterlockState
+	SetIndicatorState(commoninterface.IndicatorState)
+	GetIndicatorState() commoninterface.IndicatorState
+}
+
+// ConfigurableOven is an oven whose hardware settings, as the sensors sampling, thresholds and voting, can be changed.
+// InitConfig is called with the worker locked, it must not call the worker back.
+type ConfigurableOven interface {
+	InitConfig(config.Config)
 }
 
 type OvenProgramWorker struct {
@@ -42,9 +57,80 @@ type OvenProgramWorker struct {
 	lastPointsToBeWritten              int
 	closedAir                          bool
 	logger                             commoninterface.Logger
+	filter                             TemperatureFilter
+	lidOpenTimeout                     time.Duration
+	paused                             bool
+	diagnosticsConfig                  config.DiagnosticsConfig
+	diagnostics                        *commoninterface.DiagnosticsReport
+	publisher                          events.Publisher
+	segmentName                        string
+	configuration                      config.Config
+	//recording is true while a program writes in the run runName, not during the diagnostics and the power tests
+	recording bool
+	//nextConfiguration is an edited configuration, applied when the next program starts
+	nextConfiguration *config.Config
+	//nextOvenConfiguration is a configuration edited while working, applied to the oven when the work ends
+	nextOvenConfiguration *config.Config
+	eventLogFile          string
+	cooldownConfig        config.CooldownConfig
+	cooldownStop          chan struct{}
+	cooldownDone          chan struct{}
+}
+
+// WorkerState is the state of the worker sent to the streaming clients at each change
+type WorkerState struct {
+	IsWorking   bool                           `json:"is-working"`
+	IsPaused    bool                           `json:"is-paused"`
+	CoolingDown bool                           `json:"cooling-down"`
+	ProgramName string                         `json:"program-name"`
+	SegmentName string                         `json:"segment-name"`
+	Indicator   commoninterface.IndicatorState `json:"indicator"`
+}
+
+// WithEventPublisher sets where the data points and the state changes are published
+func WithEventPublisher(publisher events.Publisher) func(*OvenProgramWorker) {
+	return func(o *OvenProgramWorker) {
+		o.publisher = publisher
+	}
+}
+
+// GetState returns the state of the worker
+func (d *OvenProgramWorker) GetState() WorkerState {
+	d.mu.RLock()
+	state := WorkerState{IsWorking: d.isWorking, IsPaused: d.paused, CoolingDown: d.cooldownStop != nil, ProgramName: d.programName, SegmentName: d.segmentName}
+	d.mu.RUnlock()
+	state.Indicator = d.oven.GetIndicatorState()
+	return state
+}
+
+func (d *OvenProgramWorker) publish(eventType string, data any) {
+	if d.publisher != nil {
+		d.publisher.Publish(eventType, data)
+	}
+}
+
+func (d *OvenProgramWorker) publishState() {
+	d.publish(events.State, d.GetState())
+}
+
+// setIndicator sets the oven indicator and publishes the new state
+func (d *OvenProgramWorker) setIndicator(state commoninterface.IndicatorState) {
+	d.oven.SetIndicatorState(state)
+	d.publishState()
+}
+
+// addDataPoint adds the point to the history, to be saved and published
+func (d *OvenProgramWorker) addDataPoint(p ProgramDataPoint) {
+	d.mu.Lock()
+	d.programHistory = append(d.programHistory, p)
+	d.mu.Unlock()
+	d.lastPointsToBeWritten++
+	d.publish(events.DataPoint, p)
 }
 
-func (d OvenProgramWorker) GetRunningProgram() string {
+func (d *OvenProgramWorker) GetRunningProgram() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	return d.programName
 }
 func (d OvenProgramWorker) GetTimeSeconds() float64 {
@@ -60,13 +146,36 @@ func (d *OvenProgramWorker) IsWorking() bool {
 	return d.isWorking
 }
 
+// IsPaused returns true while the program is waiting for the lid to be closed
+func (d *OvenProgramWorker) IsPaused() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.paused
+}
+
+func (d *OvenProgramWorker) setPaused(paused bool) {
+	d.mu.Lock()
+	d.paused = paused
+	d.mu.Unlock()
+	d.publishState()
+}
+
+// AcknowledgeIndicator stops the finished or fault signal, returning the indicator to idle
+func (d *OvenProgramWorker) AcknowledgeIndicator() {
+	if d.IsWorking() || !d.oven.GetIndicatorState().NeedsAcknowledge() {
+		return
+	}
+	d.setIndicator(commoninterface.IndicatorIdle)
+}
+
 func (d *OvenProgramWorker) RequestStopProgram() {
 	d.mu.Lock()
-	defer d.mu.Unlock()
 	d.endRequest = true
+	d.mu.Unlock()
+	d.LogEvent(eventlog.Info, eventlog.CategoryProgram, "stop requested", nil)
 }
 
-func (d OvenProgramWorker) shouldStopProgram() bool {
+func (d *OvenProgramWorker) shouldStopProgram() bool {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	return d.endRequest
@@ -90,12 +199,21 @@ func (d *OvenProgramWorker) endedProgram() {
 		d.logger.Info("OvenProgramWorker: endedProgram")
 	}
 	d.Save()
+	d.mu.Lock()
 	d.programName = ""
+	d.segmentName = ""
+	d.mu.Unlock()
 	os.Remove(filepath.Join(d.SavedRunFolder, "work.txt"))
 	d.oven.SetPercentual(0)
 	d.oven.EndProgram()
 }
 func (d *OvenProgramWorker) changedStepPoint(s StepPoint) error {
+	d.mu.Lock()
+	d.segmentName = s.SegmentName
+	d.mu.Unlock()
+	d.publishState()
+	d.LogEvent(eventlog.Info, eventlog.CategorySegment, "segment started", map[string]any{
+		"segment": s.SegmentName, "temperature": s.Temperature, "time-minutes": s.TimeMinutes})
 	f, err := os.OpenFile(filepath.Join(d.SavedRunFolder, "work.txt"), os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
@@ -119,73 +237,172 @@ func (d *OvenProgramWorker) StartOvenProgram(program OvenProgram, runName string
 	d.isWorking = true
 	d.endRequest = false
 	d.mu.Unlock()
+	//the cooldown of the previous run is still writing in its run
+	d.stopCooldown()
+	d.mu.Lock()
+	d.applyNextConfig()
 	d.programName = program.Name
+	//a resumed program keeps writing in its run
+	d.runName = runName
 	if runName == "" {
 		d.runName = time.Now().Format("2006-01-02T15-04-05") + "-" + program.Name
 	}
+	d.recording = true
 	d.programHistory = make([]ProgramDataPoint, 0)
+	d.mu.Unlock()
 	d.lastPointsToBeWritten = 0
 	d.startedProgram()
+	d.startRunMetadata(program, runName != "")
+	if runName != "" {
+		d.LogEvent(eventlog.Warning, eventlog.CategoryProgram, "program resumed after a power loss", map[string]any{"program": program.Name})
+	} else {
+		d.LogEvent(eventlog.Info, eventlog.CategoryProgram, "program started", map[string]any{"program": program.Name})
+	}
+	d.publishState()
 	go func(program OvenProgram) {
 		if program.AirCloseAtDegrees <= 0 {
-			d.oven.CloseAir()
+			d.closeAir()
 		} else {
-			d.oven.OpenAir()
+			d.openAir()
 		}
+		//a resumed run continues its time after the last point saved, not from 0
 		d.timeSeconds = 0
+		if runName != "" {
+			d.timeSeconds = d.lastRunSeconds(runName)
+		}
 		if len(program.Points) == 0 {
 			return
 		}
+		//the indicator shows how the program ended: finished and fault beep until acknowledged
+		outcome := commoninterface.IndicatorFault
 		defer func() {
 			d.mu.Lock()
 			d.isWorking = false
+			d.recording = false
+			d.applyNextOvenConfig()
 			d.mu.Unlock()
 			d.endedProgram()
+			d.endRunMetadata(d.runName, runOutcome(outcome), time.Now())
+			d.logProgramEnd(d.runName, runOutcome(outcome))
+			d.setIndicator(outcome)
+			d.startCooldown(d.runName, program.Name, d.cooldownConfig)
 		}()
 		if err := d.oven.InitStartProgram(); err != nil {
 			return
 		}
-		d.writeHeader()
+		if err := runfile.Create(d.runFilePathFor(d.runName), len(d.oven.GetSensorTemperatures())); err != nil && d.logger != nil {
+			d.logger.Error("OvenProgramWorker: cannot create run file", "err", err)
+		}
 		firstPoint := program.Points[0]
 		d.changedStepPoint(firstPoint)
-		temperature, err := d.oven.GetTemperature()
+		d.filter.Reset()
+		_, temperature, err := d.readTemperature()
 		if err != nil {
 			return
 		}
-		if firstPoint.Temperature > temperature {
-			d.doRamp(firstPoint, true, program.AirCloseAtDegrees)
-		} else if firstPoint.Temperature == temperature {
-			d.maintainTemperature(firstPoint)
-		} else {
-			d.doRamp(firstPoint, false, program.AirCloseAtDegrees)
+		if err := d.executeStep(firstPoint, temperature, program.AirCloseAtDegrees); isAbortError(err) {
+			return
 		}
 		if d.shouldStopProgram() {
+			outcome = commoninterface.IndicatorIdle
 			return
 		}
 		lastTemp := firstPoint.Temperature
 		for _, s := range program.Points[1:] {
 			d.changedStepPoint(s)
-			if s.Temperature > lastTemp {
-				d.doRamp(s, true, program.AirCloseAtDegrees)
-			} else if s.Temperature == lastTemp {
-				d.maintainTemperature(s)
-			} else {
-				d.doRamp(s, false, program.AirCloseAtDegrees)
+			if err := d.executeStep(s, lastTemp, program.AirCloseAtDegrees); isAbortError(err) {
+				return
 			}
 			lastTemp = s.Temperature
 			if d.shouldStopProgram() {
-				d.Save()
-				d.programName = ""
+				outcome = commoninterface.IndicatorIdle
 				return
 			}
 		}
+		outcome = commoninterface.IndicatorFinished
 	}(program)
 }
 
+// executeStep does a ramp or keeps the temperature depending on the step temperature compared to the starting one.
+// A safety trip from the oven, the lid left open too long or the temperature not read, is logged and returned,
+// so that the program is stopped as faulted.
+func (d *OvenProgramWorker) executeStep(s StepPoint, fromTemperature float64, airCloseAtDegrees float64) error {
+	var err error
+	if s.Temperature > fromTemperature {
+		d.setIndicator(commoninterface.IndicatorHeating)
+		err = d.doRamp(s, true, airCloseAtDegrees)
+	} else if s.Temperature == fromTemperature {
+		d.setIndicator(commoninterface.IndicatorHolding)
+		err = d.maintainTemperature(s)
+	} else {
+		d.setIndicator(commoninterface.IndicatorCooling)
+		err = d.doRamp(s, false, airCloseAtDegrees)
+	}
+	if isAbortError(err) {
+		if d.logger != nil {
+			d.logger.Error("OvenProgramWorker: stopping program", "error", err.Error())
+		}
+		d.LogEvent(eventlog.Critical, eventlog.CategorySafety, "program aborted", map[string]any{"error": err.Error()})
+	}
+	return err
+}
+
+// ErrSensorFault aborts a program whose temperature cannot be read
+var ErrSensorFault = errors.New("sensor fault")
+
+// maxSensorReadErrors are the consecutive temperature read errors that abort a program
+const maxSensorReadErrors = 3
+
+func isAbortError(err error) bool {
+	return errors.Is(err, commoninterface.ErrSafetyTrip) || errors.Is(err, commoninterface.ErrLidOpenTimeout) ||
+		errors.Is(err, ErrSensorFault)
+}
+
+// waitLidClosed pauses the program while the lid is open: the power is cut and the program time does not advance.
+// It returns true if the program has been paused, and ErrLidOpenTimeout if the lid has not been closed in time.
+func (d *OvenProgramWorker) waitLidClosed() (bool, error) {
+	if !d.oven.GetInterlocks().LidOpen {
+		return false, nil
+	}
+	if d.logger != nil {
+		d.logger.Info("OvenProgramWorker: lid open, program paused")
+	}
+	d.setPaused(true)
+	defer d.setPaused(false)
+	d.LogEvent(eventlog.Warning, eventlog.CategoryLid, "lid open, program paused", nil)
+	previousState := d.oven.GetIndicatorState()
+	d.setIndicator(commoninterface.IndicatorPaused)
+	defer d.setIndicator(previousState)
+	d.oven.SetPercentual(0)
+	deadline := time.Now().Add(d.lidOpenTimeout)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if d.shouldStopProgram() {
+			return true, nil
+		}
+		interlocks := d.oven.GetInterlocks()
+		if interlocks.EmergencyStop {
+			return true, fmt.Errorf("%w: emergency stop pressed while paused", commoninterface.ErrSafetyTrip)
+		}
+		if !interlocks.LidOpen {
+			if d.logger != nil {
+				d.logger.Info("OvenProgramWorker: lid closed, program resumed")
+			}
+			d.LogEvent(eventlog.Info, eventlog.CategoryLid, "lid closed, program resumed", nil)
+			return true, nil
+		}
+		if now.After(deadline) {
+			return true, fmt.Errorf("%w: open for more than %v", commoninterface.ErrLidOpenTimeout, d.lidOpenTimeout)
+		}
+	}
+	return true, nil
+}
+
 func (d *OvenProgramWorker) doRamp(s StepPoint, isUpRamp bool, airCloseAtDegrees float64) error {
 	var err error
 
-	d.TargetTemperature, err = d.oven.GetTemperature()
+	_, d.TargetTemperature, err = d.readTemperature()
 	if err != nil {
 		if d.logger != nil {
 			d.logger.Error("OvenProgramWorker: doRamp", "error", err.Error())
@@ -197,30 +414,45 @@ func (d *OvenProgramWorker) doRamp(s StepPoint, isUpRamp bool, airCloseAtDegrees
 	ovenTemperature := d.TargetTemperature
 	timeSave := 0.0
 	lastNow := time.Now()
-	step, newTemperature := 0.0, 0.0
+	step, newTemperature, rawTemperature := 0.0, 0.0, 0.0
+	readErrors := 0
 	d.ticker = time.NewTicker(time.Duration(d.stepTime) * time.Second)
 	defer d.ticker.Stop()
 	for now := range d.ticker.C {
 		if d.shouldStopProgram() {
 			break
 		}
+		if paused, err := d.waitLidClosed(); err != nil {
+			return err
+		} else if paused {
+			lastNow = time.Now()
+			continue
+		}
 		if ((ovenTemperature >= s.Temperature) && isUpRamp) || ((ovenTemperature <= s.Temperature) && !isUpRamp) {
 			break
 		}
 		if !d.closedAir && isUpRamp && ovenTemperature >= airCloseAtDegrees {
-			d.oven.CloseAir()
+			d.closeAir()
 			d.closedAir = true
 		}
 		step = (now.Sub(lastNow)).Seconds()
 		lastNow = now
 		d.timeSeconds += step
 		timeSave += step
-		newTemperature, err = d.oven.GetTemperature()
+		rawTemperature, newTemperature, err = d.readTemperature()
 		if err != nil {
 			if d.logger != nil {
 				d.logger.Error("OvenProgramWorker: doRamp", "error", err.Error())
 			}
-			return err
+			if err := d.sensorReadFailed(&readErrors, err); err != nil {
+				return err
+			}
+			continue
+		}
+		readErrors = 0
+		coldJunction, err := d.oven.GetColdJunctionTemperature()
+		if err != nil && !errors.Is(err, commoninterface.ErrNotSupported) && d.logger != nil {
+			d.logger.Error("OvenProgramWorker: doRamp readColdJunction", "error", err.Error())
 		}
 		temperatureVariance = newTemperature - ovenTemperature
 		ovenTemperature = newTemperature
@@ -240,11 +472,9 @@ func (d *OvenProgramWorker) doRamp(s StepPoint, isUpRamp bool, airCloseAtDegrees
 		actualPercentual = max(actualPercentual, 0)
 		d.oven.SetPercentual(actualPercentual)
 		previousError = errorValue
-		d.programHistory = append(d.programHistory, createDataPoint(d.programName, s.SegmentName, d.timeSeconds, d.TargetTemperature, newTemperature, actualPercentual, d.closedAir))
-		d.lastPointsToBeWritten++
+		d.addDataPoint(createDataPoint(d.programName, s.SegmentName, d.timeSeconds, d.TargetTemperature, newTemperature, rawTemperature, actualPercentual, d.closedAir, coldJunction, d.oven.GetSensorTemperatures()))
 		if timeSave > d.stepSave {
 			d.Save()
-			d.lastPointsToBeWritten = 0
 			timeSave = 0
 		}
 	}
@@ -253,19 +483,20 @@ func (d *OvenProgramWorker) doRamp(s StepPoint, isUpRamp bool, airCloseAtDegrees
 }
 func (d *OvenProgramWorker) maintainTemperature(s StepPoint) error {
 	var err error
-	d.TargetTemperature, err = d.oven.GetTemperature()
+	_, d.TargetTemperature, err = d.readTemperature()
 	if err != nil {
 		if d.logger != nil {
 			d.logger.Error("OvenProgramWorker: maintainTemperature", "error", err.Error())
 		}
-		return err
+		return fmt.Errorf("%w: %w", ErrSensorFault, err)
 	}
 	integral, previousError, derivative := 0.0, 0.0, 0.0
 	d.TargetTemperature = s.Temperature
 	first := true
-	ovenTemperature := 0.0
+	ovenTemperature, rawTemperature := 0.0, 0.0
 	timeSave := 0.0
 	totalTime := 0.0
+	readErrors := 0
 	lastNow := time.Now()
 	step := 0.0
 	d.ticker = time.NewTicker(time.Duration(d.stepTime) * time.Second)
@@ -274,6 +505,12 @@ func (d *OvenProgramWorker) maintainTemperature(s StepPoint) error {
 		if d.shouldStopProgram() {
 			break
 		}
+		if paused, err := d.waitLidClosed(); err != nil {
+			return err
+		} else if paused {
+			lastNow = time.Now()
+			continue
+		}
 		if totalTime >= s.TimeSeconds() {
 			break
 		}
@@ -282,12 +519,20 @@ func (d *OvenProgramWorker) maintainTemperature(s StepPoint) error {
 		lastNow = now
 		d.timeSeconds += step
 		timeSave += step
-		ovenTemperature, err = d.oven.GetTemperature()
+		rawTemperature, ovenTemperature, err = d.readTemperature()
 		if err != nil {
 			if d.logger != nil {
 				d.logger.Error("OvenProgramWorker: maintainTemperature readTemperature", "error", err.Error())
 			}
-			return err
+			if err := d.sensorReadFailed(&readErrors, err); err != nil {
+				return err
+			}
+			continue
+		}
+		readErrors = 0
+		coldJunction, err := d.oven.GetColdJunctionTemperature()
+		if err != nil && !errors.Is(err, commoninterface.ErrNotSupported) && d.logger != nil {
+			d.logger.Error("OvenProgramWorker: maintainTemperature readColdJunction", "error", err.Error())
 		}
 		errorValue := s.Temperature - ovenTemperature
 		if first {
@@ -303,17 +548,36 @@ func (d *OvenProgramWorker) maintainTemperature(s StepPoint) error {
 		actualPercentual = max(actualPercentual, 0)
 		d.oven.SetPercentual(actualPercentual)
 		previousError = errorValue
-		d.programHistory = append(d.programHistory, createDataPoint(d.programName, s.SegmentName, d.timeSeconds, d.TargetTemperature, ovenTemperature, actualPercentual, d.closedAir))
-		d.lastPointsToBeWritten++
+		d.addDataPoint(createDataPoint(d.programName, s.SegmentName, d.timeSeconds, d.TargetTemperature, ovenTemperature, rawTemperature, actualPercentual, d.closedAir, coldJunction, d.oven.GetSensorTemperatures()))
 		if timeSave > d.stepSave {
 			d.Save()
-			d.lastPointsToBeWritten = 0
 			timeSave = 0
 		}
 	}
 	d.Save()
 	return nil
 }
+
+// sensorReadFailed cuts the power while the temperature cannot be read, and returns ErrSensorFault to abort the
+// program after maxSensorReadErrors reads failed in a row
+func (d *OvenProgramWorker) sensorReadFailed(readErrors *int, err error) error {
+	d.oven.SetPercentual(0)
+	if *readErrors++; *readErrors >= maxSensorReadErrors {
+		return fmt.Errorf("%w: %w", ErrSensorFault, err)
+	}
+	return nil
+}
+
+// readTemperature returns the temperature read from the oven and the filtered one, used by the controller
+func (d *OvenProgramWorker) readTemperature() (raw, filtered float64, err error) {
+	raw, err = d.oven.GetTemperature()
+	if err != nil {
+		d.LogEvent(eventlog.Error, eventlog.CategorySensor, "temperature read failed", map[string]any{"error": err.Error()})
+		return 0, 0, err
+	}
+	return raw, d.filter.Filter(raw), nil
+}
+
 func (d *OvenProgramWorker) SetPowerOneMinute(pwr float64) error {
 	d.mu.Lock()
 	if d.isWorking {
@@ -322,6 +586,7 @@ func (d *OvenProgramWorker) SetPowerOneMinute(pwr float64) error {
 	}
 	d.isWorking = true
 	d.mu.Unlock()
+	d.stopCooldown()
 	err := d.oven.SetPercentual(0)
 	if err != nil {
 		return err
@@ -333,6 +598,7 @@ func (d *OvenProgramWorker) SetPowerOneMinute(pwr float64) error {
 			d.oven.EndProgram()
 			d.mu.Lock()
 			d.isWorking = false
+			d.applyNextOvenConfig()
 			d.mu.Unlock()
 		}()
 		d.oven.SetPercentual(pwr)
@@ -341,44 +607,79 @@ func (d *OvenProgramWorker) SetPowerOneMinute(pwr float64) error {
 	}(pwr)
 	return nil
 }
+
+// Save appends to the run file the points not yet written
 func (d *OvenProgramWorker) Save() error {
-	f, err := os.OpenFile(filepath.Join(d.SavedRunFolder, d.runName+".txt"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+	if d.runName == "" || d.lastPointsToBeWritten == 0 {
+		return nil
+	}
+	if err := runfile.Append(d.runFilePathFor(d.runName), d.programHistory[len(d.programHistory)-d.lastPointsToBeWritten:]); err != nil {
+		if d.logger != nil {
+			d.logger.Error("OvenProgramWorker: cannot save run", "err", err)
+		}
 		return err
 	}
-	defer f.Close()
+	d.lastPointsToBeWritten = 0
+	return nil
+}
 
-	encoder := csv.NewWriter(f)
-	err = encoder.WriteAll(d.programHistory[len(d.programHistory)-d.lastPointsToBeWritten:].toStrings())
-	return err
+// applyConfig sets the parameters of the programs from the configuration. The run folder and the event log are set once.
+func (d *OvenProgramWorker) applyConfig(config config.Config) {
+	d.stepTime = config.Controller.StepTime
+	d.stepSave = config.Controller.StepSave
+	d.kdMaintain = config.Controller.KdMaintain
+	d.kiMaintain = config.Controller.KiMaintain
+	d.kpMaintain = config.Controller.KpMaintain
+	d.kdRamp = config.Controller.KdRamp
+	d.kiRamp = config.Controller.KiRamp
+	d.kpRamp = config.Controller.KpRamp
+	d.filter = NewTemperatureFilter(config)
+	d.diagnosticsConfig = newDiagnosticsConfig(config)
+	d.lidOpenTimeout = time.Duration(config.Controller.LidOpenTimeoutSeconds * float64(time.Second))
+	d.configuration = config
+	d.cooldownConfig = config.Cooldown
 }
-func (d *OvenProgramWorker) writeHeader() error {
-	f, err := os.OpenFile(filepath.Join(d.SavedRunFolder, d.runName+".txt"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+
+// UpdateConfig gives the worker an edited configuration. A running program keeps the one it started with,
+// the new one is used from the next program. The oven hardware settings are changed now if nothing is running,
+// otherwise when the program, the diagnostics or the power test end.
+func (d *OvenProgramWorker) UpdateConfig(config config.Config) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextConfiguration = &config
+	d.nextOvenConfiguration = &config
+	if !d.isWorking {
+		d.applyNextOvenConfig()
 	}
-	defer f.Close()
+}
 
-	encoder := csv.NewWriter(f)
-	err = encoder.Write(programHistoryHeaders())
-	encoder.Flush()
-	return err
+// applyNextOvenConfig applies the edited configuration to the oven, if any. It is called with d.mu held and nothing running.
+func (d *OvenProgramWorker) applyNextOvenConfig() {
+	if oven, ok := d.oven.(ConfigurableOven); ok && d.nextOvenConfiguration != nil {
+		oven.InitConfig(*d.nextOvenConfiguration)
+	}
+	d.nextOvenConfiguration = nil
 }
 
-func NewOvenProgramWorker(oven Oven, config config.Config, ovenProgramManager OvenProgramManager, logger commoninterface.Logger) *OvenProgramWorker {
+// applyNextConfig applies the edited configuration, if any. It is called with d.mu held and nothing running.
+func (d *OvenProgramWorker) applyNextConfig() {
+	if d.nextConfiguration != nil {
+		d.applyConfig(*d.nextConfiguration)
+		d.nextConfiguration = nil
+	}
+}
+
+func NewOvenProgramWorker(oven Oven, config config.Config, ovenProgramManager OvenProgramManager, logger commoninterface.Logger, options ...func(*OvenProgramWorker)) *OvenProgramWorker {
 	o := OvenProgramWorker{oven: oven}
 	o.mu = &sync.RWMutex{}
 	o.isWorking = false
-	o.stepTime = config.Controller.StepTime
-	o.stepSave = config.Controller.StepSave
-	o.kdMaintain = config.Controller.KdMaintain
-	o.kiMaintain = config.Controller.KiMaintain
-	o.kpMaintain = config.Controller.KpMaintain
-	o.kdRamp = config.Controller.KdRamp
-	o.kiRamp = config.Controller.KiRamp
-	o.kpRamp = config.Controller.KpRamp
+	o.applyConfig(config)
 	o.SavedRunFolder = config.Controller.SavedRunFolder
+	o.eventLogFile = config.Controller.EventLogFile
 	o.logger = logger
+	for _, option := range options {
+		option(&o)
+	}
 	if _, err := os.Stat(o.SavedRunFolder); err != nil {
 		if os.IsNotExist(err) {
 			if err := os.Mkdir(o.SavedRunFolder, os.ModePerm); err != nil {
@@ -405,6 +706,18 @@ func NewOvenProgramWorker(oven Oven, config config.Config, ovenProgramManager Ov
 			o.endedProgram()
 			return &o
 		}
+		if len(rec) < 3 {
+			//the program was interrupted before its first segment, nothing has been recorded
+			o.endedProgram()
+			return &o
+		}
+		found := false
+		//a run not resumed has been ended by the power loss
+		defer func() {
+			if !found {
+				o.powerLossRunMetadata(rec[2])
+			}
+		}()
 		program, ok := ovenProgramManager.Programs()[rec[0]]
 		if !ok {
 			if logger != nil {
@@ -415,29 +728,16 @@ func NewOvenProgramWorker(oven Oven, config config.Config, ovenProgramManager Ov
 		}
 		newProgram := OvenProgram{Name: program.Name, AirCloseAtDegrees: program.AirCloseAtDegrees}
 
-		fjob, err := os.OpenFile(filepath.Join(o.SavedRunFolder, rec[2]+".txt"), os.O_RDONLY, 0644)
-		if err != nil {
+		history, err := runfile.ReadFile(o.runFilePathFor(rec[2]))
+		if err != nil || len(history) == 0 {
 			if logger != nil {
-				logger.Error("NewOvenWorker: Cannot open run file", "err", err)
+				logger.Error("NewOvenWorker: Cannot read run file", "err", err)
 			}
 			o.endedProgram()
 			return &o
 		}
-		defer fjob.Close()
-		reader := csv.NewReader(fjob)
-		history, err := reader.ReadAll()
-		if err != nil {
-			if logger != nil {
-				logger.Error("NewOvenWorker: Cannot open run file as csv", "err", err)
-			}
-			o.endedProgram()
-			return &o
-		}
-		o.programHistory = programDataPointArrayFromDataStrings(history)
-		found := false
-
-		lastTimeStr := o.programHistory[len(o.programHistory)-1].DateTime
-		lastTime, err := time.Parse("2006-01-02T15:04:05", lastTimeStr)
+		o.programHistory = ProgramDataPointArray(history)
+		lastTime, err := runfile.ParseTime(o.programHistory[len(o.programHistory)-1].DateTime)
 		if err != nil {
 			if logger != nil {
 				logger.Error("NewOvenWorker: Cannot read last time", "err", err)
@@ -462,37 +762,58 @@ func NewOvenProgramWorker(oven Oven, config config.Config, ovenProgramManager Ov
 		}
 		if found {
 			o.StartOvenProgram(newProgram, rec[2])
+		} else {
+			os.Remove(filepath.Join(o.SavedRunFolder, "work.txt"))
 		}
 	}
 
 	return &o
 }
 
+// GetAllDataActualWork returns every step-th point of the actual run
 func (d *OvenProgramWorker) GetAllDataActualWork(step int) ProgramDataPointArray {
-	if step == 1 {
-		return d.programHistory
-	} else {
-		programHistoryLn := len(d.programHistory)
-		res := make([]ProgramDataPoint, programHistoryLn/step+1)
-
-		for i := 0; i < programHistoryLn; i += step {
-			res[i/step] = d.programHistory[i]
-		}
-		return res
+	history := d.actualHistory()
+	if step <= 1 {
+		return history
 	}
+	res := make(ProgramDataPointArray, 0, (len(history)+step-1)/step)
+	for i := 0; i < len(history); i += step {
+		res = append(res, history[i])
+	}
+	return res
 }
 
-func (d *OvenProgramWorker) GetEndedRunList() ([]string, error) {
-	listRun := make([]string, 0)
-	err := filepath.Walk(d.SavedRunFolder, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			listRun = append(listRun, info.Name())
-		}
-		return err
-	})
+// actualHistory returns the points of the actual run. The points are only appended, so the slice can be read
+// while the run goes on.
+func (d *OvenProgramWorker) actualHistory() ProgramDataPointArray {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.programHistory
+}
 
-	return listRun, err
+// ProcessData is a part of the points of the actual run. Next is the index to ask for the following points.
+type ProcessData struct {
+	Run    string                `json:"run"`
+	Total  int                   `json:"total"`
+	Next   int                   `json:"next"`
+	Points ProgramDataPointArray `json:"points"`
+}
+
+// GetProcessData returns the points of the actual run from the index since, with the seconds from start in
+// [from, to] (to 0 up to the end), downsampled to maxPoints if not 0
+func (d *OvenProgramWorker) GetProcessData(since int, from, to float64, maxPoints int) ProcessData {
+	d.mu.RLock()
+	history, runName := d.programHistory, d.runName
+	d.mu.RUnlock()
+	data := ProcessData{Run: runName, Total: len(history), Next: len(history)}
+	since = min(max(since, 0), len(history))
+	data.Points = history[since:]
+	if from > 0 || to > 0 {
+		data.Points = data.Points.Between(from, to)
+	}
+	data.Points = data.Points.Downsample(maxPoints)
+	if data.Points == nil {
+		data.Points = ProgramDataPointArray{}
+	}
+	return data
 }
@@ -0,0 +1,122 @@
+package ovenprograms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/dummyinterface"
+)
+
+// longProgram is a slow ramp, that does not end during the tests
+var longProgram = OvenProgram{Name: "bisque", Points: []StepPoint{{SegmentName: "up", Temperature: 1000, TimeMinutes: 600}}}
+
+// newSimulatedWorker returns a worker on the simulated oven, with a controller step of a second
+func newSimulatedWorker(t *testing.T) (*dummyinterface.DummyController, *OvenProgramWorker) {
+	var c config.Config
+	c.Simulation = config.DefaultSimulationConfig()
+	c.Oven.MaxPower = 3000
+	c.Oven.Weight, c.Oven.ThermalCapacity = 100, 1000
+	c.Controller.StepTime, c.Controller.StepSave = 1, 1
+	c.Controller.KpRamp = 0.5
+	c.Controller.SavedRunFolder = t.TempDir()
+	oven := dummyinterface.NewDummyController()
+	oven.InitConfig(c)
+	t.Cleanup(oven.Terminate)
+	d := NewOvenProgramWorker(oven, c, OvenProgramManager{}, nil)
+	t.Cleanup(d.stopCooldown)
+	return oven, d
+}
+
+// waitIdle waits for the program to end
+func waitIdle(t *testing.T, d *OvenProgramWorker) {
+	deadline := time.Now().Add(5 * time.Second)
+	for d.IsWorking() {
+		if time.Now().After(deadline) {
+			t.Fatal("the program did not stop")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TestSensorFault runs a program on the simulated oven with its thermocouple failing for a while
+func TestSensorFault(t *testing.T) {
+	tests := []struct {
+		name        string
+		fault       dummyinterface.Fault
+		wantOutcome RunOutcome
+	}{
+		{name: "fault until cleared aborts", fault: dummyinterface.Fault{Type: dummyinterface.FaultOpenCircuit, AfterSeconds: 1.5},
+			wantOutcome: RunFaulted},
+		//the fault lasts less than maxSensorReadErrors reads
+		{name: "short fault is ridden out", fault: dummyinterface.Fault{Type: dummyinterface.FaultOpenCircuit, AfterSeconds: 1.5, DurationSeconds: 1.5},
+			wantOutcome: RunStopped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			oven, d := newSimulatedWorker(t)
+
+			if _, err := oven.InjectFault(tt.fault); err != nil {
+				t.Fatalf("InjectFault: %v", err)
+			}
+			d.StartOvenProgram(longProgram, "")
+			runName := d.runName
+			//the aborted run ends by the fourth read, the other keeps running
+			time.Sleep(5 * time.Second)
+			if tt.wantOutcome == RunStopped {
+				if !d.IsWorking() {
+					t.Fatal("the program stopped for the short fault")
+				}
+				d.RequestStopProgram()
+			}
+			waitIdle(t, d)
+
+			metadata, err := d.GetRunMetadata(runName)
+			if err != nil {
+				t.Fatalf("GetRunMetadata: %v", err)
+			}
+			if metadata.Outcome != tt.wantOutcome {
+				t.Errorf("outcome = %s, want %s", metadata.Outcome, tt.wantOutcome)
+			}
+			if oven.IsWorking() || oven.GetPercentual() != 0 {
+				t.Errorf("oven working %v at %v after the run, want the power off", oven.IsWorking(), oven.GetPercentual())
+			}
+			wantIndicator := commoninterface.IndicatorIdle
+			if tt.wantOutcome == RunFaulted {
+				wantIndicator = commoninterface.IndicatorFault
+			}
+			if indicator := oven.GetIndicatorState(); indicator != wantIndicator {
+				t.Errorf("indicator = %s, want %s", indicator, wantIndicator)
+			}
+		})
+	}
+}
+
+// TestUpdateConfig checks that the oven hardware is not reconfigured during a program
+func TestUpdateConfig(t *testing.T) {
+	oven, d := newSimulatedWorker(t)
+	withMaxPower := func(maxPower float64) config.Config {
+		c := d.configuration
+		c.Oven.MaxPower = maxPower
+		return c
+	}
+	d.UpdateConfig(withMaxPower(5000))
+	if maxPower := oven.GetMaxPower(); maxPower != 5000 {
+		t.Fatalf("max power when idle = %v, want the edited 5000", maxPower)
+	}
+
+	d.StartOvenProgram(longProgram, "")
+	time.Sleep(1500 * time.Millisecond)
+	d.UpdateConfig(withMaxPower(2000))
+	time.Sleep(1500 * time.Millisecond)
+	if maxPower := oven.GetMaxPower(); maxPower != 5000 {
+		t.Errorf("max power during the program = %v, want the one it started with, 5000", maxPower)
+	}
+	d.RequestStopProgram()
+	waitIdle(t, d)
+	if maxPower := oven.GetMaxPower(); maxPower != 2000 {
+		t.Errorf("max power after the program = %v, want the edited 2000", maxPower)
+	}
+}
@@ -0,0 +1,237 @@
+package ovenprograms
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	AlignByTime    = "time"
+	AlignBySegment = "segment"
+)
+
+var ErrInvalidAlignment = errors.New("invalid alignment, use time or segment")
+
+// CurvePoint is a point of a compared run. Seconds are from the run start, or from the segment start
+// when aligned by segment. The segment index tells apart a segment name repeated in the program.
+type CurvePoint struct {
+	Segment           string  `json:"segment"`
+	SegmentIndex      int     `json:"segment-index"`
+	Seconds           float64 `json:"seconds"`
+	TargetTemperature float64 `json:"target-temperature"`
+	OvenTemperature   float64 `json:"oven-temperature"`
+	Power             float64 `json:"power"`
+}
+
+// SegmentStats is how well a run followed the target in a segment.
+// The overshoot is the maximum of the oven temperature over the target, the time lag is the delay
+// of the oven temperature behind the target that best overlaps the two curves.
+type SegmentStats struct {
+	Index            int     `json:"index"`
+	Name             string  `json:"name"`
+	StartSeconds     float64 `json:"start-seconds"`
+	DurationSeconds  float64 `json:"duration-seconds"`
+	StartTemperature float64 `json:"start-temperature"`
+	EndTemperature   float64 `json:"end-temperature"`
+	MaxOvershoot     float64 `json:"max-overshoot"`
+	RMSError         float64 `json:"rms-error"`
+	TimeLagSeconds   float64 `json:"time-lag-seconds"`
+	EnergyKWh        float64 `json:"energy-kwh"`
+}
+
+// ComparedRun is a run with its curve and its statistics
+type ComparedRun struct {
+	RunInfo
+	EnergyKWh float64        `json:"energy-kwh"`
+	Segments  []SegmentStats `json:"segments"`
+	Curve     []CurvePoint   `json:"curve"`
+}
+
+// RunComparison is a set of runs aligned the same way
+type RunComparison struct {
+	Align string        `json:"align"`
+	Runs  []ComparedRun `json:"runs"`
+}
+
+// Segments splits the points in the segments, in order. A segment repeated later in the program is a new segment.
+func (history ProgramDataPointArray) Segments() []ProgramDataPointArray {
+	var segments []ProgramDataPointArray
+	start := 0
+	for i := 1; i <= len(history); i++ {
+		if i == len(history) || history[i].SegmentName != history[start].SegmentName {
+			segments = append(segments, history[start:i])
+			start = i
+		}
+	}
+	return segments
+}
+
+// EnergyKWh returns the energy used with the element at maxPower watts. The power of a point is kept until the next one.
+// The time going back, where a run resumed before the time continued, is skipped.
+func (history ProgramDataPointArray) EnergyKWh(maxPower float64) float64 {
+	var joules float64
+	for i := 1; i < len(history); i++ {
+		if dt := history[i].SecondsFromStart - history[i-1].SecondsFromStart; dt > 0 {
+			joules += history[i-1].OvenPercentage * maxPower * dt
+		}
+	}
+	return joules / 3.6e6
+}
+
+// segmentStats returns the statistics of a segment. The energy includes the interval up to the next segment.
+func segmentStats(index int, segment ProgramDataPointArray, next *ProgramDataPoint, maxPower float64) SegmentStats {
+	first, last := segment[0], segment[len(segment)-1]
+	stats := SegmentStats{
+		Index:            index,
+		Name:             first.SegmentName,
+		StartSeconds:     first.SecondsFromStart,
+		DurationSeconds:  last.SecondsFromStart - first.SecondsFromStart,
+		StartTemperature: first.OvenTemperature,
+		EndTemperature:   last.OvenTemperature,
+	}
+	var squares float64
+	for _, p := range segment {
+		e := p.OvenTemperature - p.DesiredTemperature
+		stats.MaxOvershoot = max(stats.MaxOvershoot, e)
+		squares += e * e
+	}
+	stats.RMSError = math.Round(math.Sqrt(squares/float64(len(segment)))*100) / 100
+	stats.MaxOvershoot = math.Round(stats.MaxOvershoot*100) / 100
+	stats.TimeLagSeconds = timeLag(segment)
+	withNext := segment
+	if next != nil {
+		withNext = append(segment[:len(segment):len(segment)], *next)
+		stats.DurationSeconds = next.SecondsFromStart - first.SecondsFromStart
+	}
+	stats.EnergyKWh = math.Round(withNext.EnergyKWh(maxPower)*1000) / 1000
+	return stats
+}
+
+// timeLagPoints are the most points of a segment used to compute the time lag, the search is quadratic
+const timeLagPoints = 500
+
+// timeLag returns the delay, up to half the segment, that minimizes the mean squared difference between
+// the oven temperature and the target delayed. The points are taken as equally spaced.
+// On a hold the target does not change and there is no lag.
+// A long segment is first reduced to timeLagPoints taking every nth point, the lag is a multiple of that interval.
+func timeLag(segment ProgramDataPointArray) float64 {
+	n := len(segment)
+	if n < 4 {
+		return 0
+	}
+	if n > timeLagPoints {
+		stride := (n + timeLagPoints - 1) / timeLagPoints
+		reduced := make(ProgramDataPointArray, 0, timeLagPoints)
+		for i := 0; i < n; i += stride {
+			reduced = append(reduced, segment[i])
+		}
+		segment, n = reduced, len(reduced)
+	}
+	lowest, highest := segment[0].DesiredTemperature, segment[0].DesiredTemperature
+	for _, p := range segment {
+		lowest, highest = min(lowest, p.DesiredTemperature), max(highest, p.DesiredTemperature)
+	}
+	if highest-lowest < 1 {
+		return 0
+	}
+	bestShift, bestError := 0, math.Inf(1)
+	for shift := 0; shift <= n/2; shift++ {
+		var squares float64
+		for i := shift; i < n; i++ {
+			e := segment[i].OvenTemperature - segment[i-shift].DesiredTemperature
+			squares += e * e
+		}
+		if mean := squares / float64(n-shift); mean < bestError {
+			bestShift, bestError = shift, mean
+		}
+	}
+	interval := (segment[n-1].SecondsFromStart - segment[0].SecondsFromStart) / float64(n-1)
+	return math.Round(float64(bestShift)*interval*10) / 10
+}
+
+// SegmentStats returns the statistics of each segment, with the element at maxPower watts
+func (history ProgramDataPointArray) SegmentStats(maxPower float64) []SegmentStats {
+	segments := history.Segments()
+	stats := make([]SegmentStats, len(segments))
+	for i, segment := range segments {
+		var next *ProgramDataPoint
+		if i+1 < len(segments) {
+			next = &segments[i+1][0]
+		}
+		stats[i] = segmentStats(i, segment, next, maxPower)
+	}
+	return stats
+}
+
+func curvePoints(history ProgramDataPointArray, fromSeconds float64, segmentIndex func(p ProgramDataPoint) int) []CurvePoint {
+	curve := make([]CurvePoint, len(history))
+	for i, p := range history {
+		curve[i] = CurvePoint{
+			Segment:           p.SegmentName,
+			SegmentIndex:      segmentIndex(p),
+			Seconds:           math.Round((p.SecondsFromStart-fromSeconds)*100) / 100,
+			TargetTemperature: p.DesiredTemperature,
+			OvenTemperature:   p.OvenTemperature,
+			Power:             p.OvenPercentage,
+		}
+	}
+	return curve
+}
+
+// CompareRun returns the statistics of a run and its curve with at most maxPoints points (all if 0).
+// When aligned by segment the points are divided among the segments.
+func CompareRun(info RunInfo, history ProgramDataPointArray, align string, maxPower float64, maxPoints int) ComparedRun {
+	compared := ComparedRun{RunInfo: info, EnergyKWh: math.Round(history.EnergyKWh(maxPower)*1000) / 1000}
+	compared.Segments = history.SegmentStats(maxPower)
+	segments := history.Segments()
+	if align == AlignBySegment {
+		compared.Curve = make([]CurvePoint, 0, len(history))
+		for index, segment := range segments {
+			segmentPoints := 0
+			if maxPoints > 0 {
+				segmentPoints = max(2, maxPoints*len(segment)/len(history))
+			}
+			compared.Curve = append(compared.Curve, curvePoints(segment.Downsample(segmentPoints), segment[0].SecondsFromStart,
+				func(ProgramDataPoint) int { return index })...)
+		}
+	} else {
+		var fromSeconds float64
+		if len(history) > 0 {
+			fromSeconds = history[0].SecondsFromStart
+		}
+		//the downsampled points are in the last segment started before them
+		compared.Curve = curvePoints(history.Downsample(maxPoints), fromSeconds, func(p ProgramDataPoint) int {
+			for index := len(segments) - 1; index > 0; index-- {
+				if segments[index][0].SecondsFromStart <= p.SecondsFromStart {
+					return index
+				}
+			}
+			return 0
+		})
+	}
+	return compared
+}
+
+// CompareRuns loads the runs and aligns them by time from start or by segment
+func (d *OvenProgramWorker) CompareRuns(ids []string, align string, maxPoints int) (RunComparison, error) {
+	if align == "" {
+		align = AlignByTime
+	}
+	if align != AlignByTime && align != AlignBySegment {
+		return RunComparison{}, ErrInvalidAlignment
+	}
+	comparison := RunComparison{Align: align, Runs: make([]ComparedRun, 0, len(ids))}
+	for _, id := range ids {
+		info, err := d.GetRunInfo(id)
+		if err != nil {
+			return RunComparison{}, fmt.Errorf("run %s: %w", id, err)
+		}
+		history, err := d.GetRun(id, 0)
+		if err != nil {
+			return RunComparison{}, err
+		}
+		comparison.Runs = append(comparison.Runs, CompareRun(info, history, align, d.RunMaxPower(id), maxPoints))
+	}
+	return comparison, nil
+}
@@ -0,0 +1,46 @@
+package ovenprograms
+
+import (
+	"testing"
+	"time"
+)
+
+// lagSegment is a ramp of 0.1 degrees per second sampled every second, followed by the oven lagSeconds later
+func lagSegment(points, lagSeconds int) ProgramDataPointArray {
+	segment := make(ProgramDataPointArray, points)
+	for i := range segment {
+		segment[i] = ProgramDataPoint{SegmentName: "up", SecondsFromStart: float64(i),
+			DesiredTemperature: float64(i) * 0.1, OvenTemperature: float64(max(0, i-lagSeconds)) * 0.1}
+	}
+	return segment
+}
+
+func TestTimeLag(t *testing.T) {
+	hold := lagSegment(100, 0)
+	for i := range hold {
+		hold[i].DesiredTemperature = 500
+	}
+	tests := []struct {
+		name    string
+		segment ProgramDataPointArray
+		want    float64
+	}{
+		{name: "too short", segment: lagSegment(3, 1), want: 0},
+		{name: "hold", segment: hold, want: 0},
+		{name: "no lag", segment: lagSegment(100, 0), want: 0},
+		{name: "ramp", segment: lagSegment(100, 12), want: 12},
+		//a ten hours segment is reduced to one point every 72 seconds
+		{name: "long ramp", segment: lagSegment(36000, 720), want: 720},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			if got := timeLag(tt.segment); got != tt.want {
+				t.Errorf("timeLag = %v, want %v", got, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("timeLag took %v", elapsed)
+			}
+		})
+	}
+}
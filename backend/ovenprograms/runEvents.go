@@ -0,0 +1,101 @@
+package ovenprograms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/eventlog"
+)
+
+const eventsFileExtension = ".events.jsonl"
+
+// LogEvent writes the event in the global event log and, during a run, in the one of the run
+func (d *OvenProgramWorker) LogEvent(severity eventlog.Severity, category, message string, data map[string]any) {
+	d.mu.RLock()
+	runName := ""
+	if d.recording {
+		runName = d.runName
+	}
+	d.mu.RUnlock()
+	d.logRunEvent(runName, severity, category, message, data)
+}
+
+// logRunEvent writes the event in the global event log and in the one of the run, if not empty
+func (d *OvenProgramWorker) logRunEvent(runName string, severity eventlog.Severity, category, message string, data map[string]any) {
+	e := eventlog.Entry{Time: time.Now(), Severity: severity, Category: category, Message: message, Run: runName, Data: data}
+	if d.eventLogFile != "" {
+		if err := eventlog.Append(d.eventLogFile, e); err != nil && d.logger != nil {
+			d.logger.Error("OvenProgramWorker: cannot write event log", "err", err)
+		}
+	}
+	if runName != "" {
+		if err := eventlog.Append(d.runRecordPath(runName, eventsFileExtension), e); err != nil && d.logger != nil {
+			d.logger.Error("OvenProgramWorker: cannot write run event log", "run", runName, "err", err)
+		}
+	}
+}
+
+// GetEvents returns the events of the global log selected by the query
+func (d *OvenProgramWorker) GetEvents(q eventlog.Query) ([]eventlog.Entry, error) {
+	if d.eventLogFile == "" {
+		return []eventlog.Entry{}, nil
+	}
+	return eventlog.Read(d.eventLogFile, q)
+}
+
+// GetRunEvents returns the events of a run selected by the query
+func (d *OvenProgramWorker) GetRunEvents(id string, q eventlog.Query) ([]eventlog.Entry, error) {
+	if _, err := d.runFilePath(id); err != nil {
+		return nil, err
+	}
+	return eventlog.Read(d.runRecordPath(id, eventsFileExtension), q)
+}
+
+// RecordAlarm logs an alarm raised by the oven
+func (d *OvenProgramWorker) RecordAlarm(alarm commoninterface.Alarm) {
+	d.LogEvent(eventlog.Error, eventlog.CategoryAlarm, alarm.Message, map[string]any{"source": alarm.Source})
+}
+
+// GetRunAlarms returns the alarms raised during a run
+func (d *OvenProgramWorker) GetRunAlarms(id string) ([]commoninterface.Alarm, error) {
+	events, err := d.GetRunEvents(id, eventlog.Query{Category: eventlog.CategoryAlarm})
+	if err != nil {
+		return nil, err
+	}
+	alarms := make([]commoninterface.Alarm, len(events))
+	for i, e := range events {
+		alarms[i] = commoninterface.Alarm{Time: e.Time, Source: fmt.Sprint(e.Data["source"]), Message: e.Message}
+	}
+	return alarms, nil
+}
+
+// openAir opens the air logging the event
+func (d *OvenProgramWorker) openAir() {
+	if err := d.oven.OpenAir(); err != nil {
+		d.LogEvent(eventlog.Error, eventlog.CategoryAir, "cannot open the air", map[string]any{"error": err.Error()})
+		return
+	}
+	d.LogEvent(eventlog.Info, eventlog.CategoryAir, "air opened", nil)
+}
+
+// closeAir closes the air logging the event
+func (d *OvenProgramWorker) closeAir() {
+	if err := d.oven.CloseAir(); err != nil {
+		d.LogEvent(eventlog.Error, eventlog.CategoryAir, "cannot close the air", map[string]any{"error": err.Error()})
+		return
+	}
+	d.LogEvent(eventlog.Info, eventlog.CategoryAir, "air closed", nil)
+}
+
+// logProgramEnd logs how a run ended. It is called when the run is not working anymore, so the run is explicit.
+func (d *OvenProgramWorker) logProgramEnd(runName string, outcome RunOutcome) {
+	severity := eventlog.Info
+	switch outcome {
+	case RunStopped, RunPowerLoss:
+		severity = eventlog.Warning
+	case RunFaulted:
+		severity = eventlog.Error
+	}
+	d.logRunEvent(runName, severity, eventlog.CategoryProgram, "program ended", map[string]any{"outcome": outcome})
+}
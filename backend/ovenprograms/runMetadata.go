@@ -0,0 +1,189 @@
+package ovenprograms
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/config"
+)
+
+const metadataFileExtension = ".json"
+
+// RunSummary are the statistics of a whole run
+type RunSummary struct {
+	Points          int     `json:"points"`
+	DurationSeconds float64 `json:"duration-seconds"`
+	PeakTemperature float64 `json:"peak-temperature"`
+	EnergyKWh       float64 `json:"energy-kwh"`
+	MaxOvershoot    float64 `json:"max-overshoot"`
+	//RMSError is the root mean square of the difference from the target over all the points
+	RMSError float64 `json:"rms-error"`
+}
+
+// RunMetadata is written when a run starts, next to its data, and updated when it ends. It records what the
+// run actually ran: the program and the configuration at that time.
+type RunMetadata struct {
+	ID      string        `json:"id"`
+	Program OvenProgram   `json:"program"`
+	Config  config.Config `json:"config"`
+	Start   time.Time     `json:"start"`
+	End     *time.Time    `json:"end,omitempty"`
+	//Outcome is running, or resumed after a power loss, until the run ends
+	Outcome   RunOutcome     `json:"outcome"`
+	ResumedAt []time.Time    `json:"resumed-at,omitempty"`
+	Summary   *RunSummary    `json:"summary,omitempty"`
+	Segments  []SegmentStats `json:"segments,omitempty"`
+	//Cooldown is the recording after the end of the program, in the segment CooldownSegment
+	Cooldown *CooldownSummary `json:"cooldown,omitempty"`
+}
+
+// runOutcome returns the outcome of a run from how the indicator shows its end
+func runOutcome(indicator commoninterface.IndicatorState) RunOutcome {
+	switch indicator {
+	case commoninterface.IndicatorFinished:
+		return RunCompleted
+	case commoninterface.IndicatorIdle:
+		return RunStopped
+	default:
+		return RunFaulted
+	}
+}
+
+// GetRunMetadata returns the metadata of a run, ErrRunNotFound also for the runs saved before the metadata existed
+func (d *OvenProgramWorker) GetRunMetadata(id string) (RunMetadata, error) {
+	if _, err := d.runFilePath(id); err != nil {
+		return RunMetadata{}, err
+	}
+	var metadata RunMetadata
+	f, err := os.Open(d.runRecordPath(id, metadataFileExtension))
+	if errors.Is(err, os.ErrNotExist) {
+		return metadata, ErrRunNotFound
+	}
+	if err != nil {
+		return metadata, err
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(&metadata)
+	return metadata, err
+}
+
+// writeRunMetadata replaces the metadata file, through a temporary file so that a power loss cannot leave it half written
+func (d *OvenProgramWorker) writeRunMetadata(metadata RunMetadata) error {
+	path := d.runRecordPath(metadata.ID, metadataFileExtension)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(metadata); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// startRunMetadata writes the metadata of a new run, or marks as resumed the one of a run restarted after a power loss
+func (d *OvenProgramWorker) startRunMetadata(program OvenProgram, resumed bool) {
+	now := time.Now()
+	metadata, err := d.GetRunMetadata(d.runName)
+	if !resumed || err != nil {
+		metadata = RunMetadata{ID: d.runName, Program: program, Config: d.configuration, Start: now, Outcome: RunRunning}
+	}
+	if resumed {
+		metadata.Outcome = RunResumed
+		metadata.ResumedAt = append(metadata.ResumedAt, now)
+		metadata.End = nil
+	}
+	if err := d.writeRunMetadata(metadata); err != nil && d.logger != nil {
+		d.logger.Error("OvenProgramWorker: cannot write run metadata", "err", err)
+	}
+}
+
+// endRunMetadata records the end of the run with its statistics, read from the whole run file
+func (d *OvenProgramWorker) endRunMetadata(runName string, outcome RunOutcome, end time.Time) {
+	metadata, err := d.GetRunMetadata(runName)
+	if err != nil {
+		if d.logger != nil {
+			d.logger.Error("OvenProgramWorker: cannot read run metadata", "run", runName, "err", err)
+		}
+		return
+	}
+	metadata.Outcome = outcome
+	metadata.End = &end
+	if history, err := LoadProgramDataPointArray(d.runRecordPath(runName, runFileExtension)); err == nil {
+		maxPower := metadata.Config.Oven.MaxPower
+		summary := RunSummary{Points: len(history), EnergyKWh: history.EnergyKWh(maxPower)}
+		var squares float64
+		for _, p := range history {
+			summary.PeakTemperature = max(summary.PeakTemperature, p.OvenTemperature)
+			summary.MaxOvershoot = max(summary.MaxOvershoot, p.OvenTemperature-p.DesiredTemperature)
+			squares += (p.OvenTemperature - p.DesiredTemperature) * (p.OvenTemperature - p.DesiredTemperature)
+		}
+		if len(history) > 0 {
+			summary.DurationSeconds = end.Sub(metadata.Start).Seconds()
+			summary.RMSError = roundTo(math.Sqrt(squares/float64(len(history))), 2)
+		}
+		summary.EnergyKWh = roundTo(summary.EnergyKWh, 3)
+		summary.DurationSeconds = roundTo(summary.DurationSeconds, 1)
+		summary.MaxOvershoot = roundTo(summary.MaxOvershoot, 2)
+		metadata.Summary = &summary
+		metadata.Segments = history.SegmentStats(maxPower)
+	} else if d.logger != nil {
+		d.logger.Error("OvenProgramWorker: cannot read run for the summary", "run", runName, "err", err)
+	}
+	if err := d.writeRunMetadata(metadata); err != nil && d.logger != nil {
+		d.logger.Error("OvenProgramWorker: cannot write run metadata", "err", err)
+	}
+}
+
+// powerLossRunMetadata records that a run has been ended by a power loss and not resumed. The end is the last
+// time the run file was written.
+func (d *OvenProgramWorker) powerLossRunMetadata(runName string) {
+	end := time.Now()
+	if info, err := os.Stat(d.runRecordPath(runName, runFileExtension)); err == nil {
+		end = info.ModTime()
+	}
+	d.logProgramEnd(runName, RunPowerLoss)
+	if _, err := d.GetRunMetadata(runName); err != nil {
+		return
+	}
+	d.endRunMetadata(runName, RunPowerLoss, end)
+}
+
+// RunMaxPower returns the power of the element when the run was fired, from its metadata. The runs saved
+// before the metadata existed use the current configuration.
+func (d *OvenProgramWorker) RunMaxPower(id string) float64 {
+	if metadata, err := d.GetRunMetadata(id); err == nil && metadata.Config.Oven.MaxPower > 0 {
+		return metadata.Config.Oven.MaxPower
+	}
+	return d.oven.GetMaxPower()
+}
+
+func roundTo(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// updateCooldownMetadata records the cooldown of a run
+func (d *OvenProgramWorker) updateCooldownMetadata(runName string, cooldown CooldownSummary) {
+	metadata, err := d.GetRunMetadata(runName)
+	if err != nil {
+		return
+	}
+	metadata.Cooldown = &cooldown
+	if err := d.writeRunMetadata(metadata); err != nil && d.logger != nil {
+		d.logger.Error("OvenProgramWorker: cannot write run metadata", "err", err)
+	}
+}
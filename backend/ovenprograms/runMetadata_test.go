@@ -0,0 +1,52 @@
+package ovenprograms
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/dummyinterface"
+	"github.com/idalmasso/ovencontrol/backend/runfile"
+)
+
+func TestRunMaxPower(t *testing.T) {
+	oven := dummyinterface.NewDummyController()
+	var c config.Config
+	c.Oven.MaxPower = 5000
+	oven.InitConfig(c)
+	t.Cleanup(oven.Terminate)
+	d := &OvenProgramWorker{oven: oven, SavedRunFolder: t.TempDir(), mu: &sync.RWMutex{}}
+	start := time.Now()
+	points := []runfile.DataPoint{
+		{SegmentName: "up", DateTime: runfile.FormatTime(start), SecondsFromStart: 0, OvenPercentage: 1},
+		{SegmentName: "up", DateTime: runfile.FormatTime(start.Add(time.Hour)), SecondsFromStart: 3600, OvenPercentage: 1},
+	}
+	for _, id := range []string{"legacy", "fired"} {
+		if err := runfile.Append(d.runFilePathFor(id), points); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	fired := RunMetadata{ID: "fired", Start: start, Outcome: RunCompleted}
+	fired.Config.Oven.MaxPower = 2000
+	if err := d.writeRunMetadata(fired); err != nil {
+		t.Fatalf("writeRunMetadata: %v", err)
+	}
+
+	tests := []struct {
+		id         string
+		wantEnergy float64
+	}{
+		{id: "fired", wantEnergy: 2},
+		{id: "legacy", wantEnergy: 5},
+	}
+	for _, tt := range tests {
+		comparison, err := d.CompareRuns([]string{tt.id}, AlignByTime, 0)
+		if err != nil {
+			t.Fatalf("CompareRuns(%s): %v", tt.id, err)
+		}
+		if got := comparison.Runs[0].EnergyKWh; got != tt.wantEnergy {
+			t.Errorf("energy of %s = %v kWh, want %v", tt.id, got, tt.wantEnergy)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package ovenprograms
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The records of a run are saved next to its data file, with the same name and their own extension
+const (
+	notesFileExtension = ".notes.json"
+)
+
+// RunNotes are the operator notes of a run
+type RunNotes struct {
+	Notes     string    `json:"notes"`
+	UpdatedAt time.Time `json:"updated-at"`
+}
+
+func (d *OvenProgramWorker) runRecordPath(id, extension string) string {
+	return filepath.Join(d.SavedRunFolder, id+extension)
+}
+
+// GetRunNotes returns the operator notes of a run, empty if never written
+func (d *OvenProgramWorker) GetRunNotes(id string) (RunNotes, error) {
+	if _, err := d.runFilePath(id); err != nil {
+		return RunNotes{}, err
+	}
+	var notes RunNotes
+	f, err := os.Open(d.runRecordPath(id, notesFileExtension))
+	if errors.Is(err, os.ErrNotExist) {
+		return notes, nil
+	}
+	if err != nil {
+		return notes, err
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(&notes)
+	return notes, err
+}
+
+// SetRunNotes replaces the operator notes of a run
+func (d *OvenProgramWorker) SetRunNotes(id string, text string) (RunNotes, error) {
+	if _, err := d.runFilePath(id); err != nil {
+		return RunNotes{}, err
+	}
+	notes := RunNotes{Notes: text, UpdatedAt: time.Now()}
+	f, err := os.Create(d.runRecordPath(id, notesFileExtension))
+	if err != nil {
+		return notes, err
+	}
+	defer f.Close()
+	return notes, json.NewEncoder(f).Encode(notes)
+}
+
+// removeRunRecords deletes the records of a deleted run
+func (d *OvenProgramWorker) removeRunRecords(id string) {
+	for _, extension := range []string{eventsFileExtension, notesFileExtension, metadataFileExtension} {
+		if err := os.Remove(d.runRecordPath(id, extension)); err != nil && !errors.Is(err, os.ErrNotExist) && d.logger != nil {
+			d.logger.Error("DeleteRun: cannot remove run record", "file", id+extension, "err", err)
+		}
+	}
+}
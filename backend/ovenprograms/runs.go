@@ -0,0 +1,236 @@
+package ovenprograms
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/idalmasso/ovencontrol/backend/runfile"
+)
+
+const (
+	runFileExtension = ".txt"
+	workFileName     = "work.txt"
+)
+
+var ErrRunNotFound = errors.New("run not found")
+
+// ErrRunInProgress is returned when deleting the run that is being recorded
+var ErrRunInProgress = errors.New("run in progress")
+
+// RunOutcome is how a saved run ended
+type RunOutcome string
+
+const (
+	RunRunning   RunOutcome = "running"
+	RunCompleted RunOutcome = "completed"
+	RunStopped   RunOutcome = "stopped"
+	RunFaulted   RunOutcome = "faulted"
+	//RunPowerLoss is a run interrupted by a power loss or a restart, and not resumed
+	RunPowerLoss RunOutcome = "power-loss"
+	//RunResumed is a run restarted after a power loss, until it ends
+	RunResumed RunOutcome = "resumed"
+	//RunEnded is a run saved without metadata, whose outcome is not known
+	RunEnded RunOutcome = "ended"
+)
+
+// RunInfo describes a saved run
+type RunInfo struct {
+	ID              string     `json:"id"`
+	ProgramName     string     `json:"program-name"`
+	Start           string     `json:"start"`
+	End             string     `json:"end"`
+	Outcome         RunOutcome `json:"outcome"`
+	DurationSeconds float64    `json:"duration-seconds"`
+	PeakTemperature float64    `json:"peak-temperature"`
+	Points          int        `json:"points"`
+	SizeBytes       int64      `json:"size-bytes"`
+}
+
+// ReadProgramDataPointArray reads a run file, of any version
+func ReadProgramDataPointArray(r io.Reader) (ProgramDataPointArray, error) {
+	points, err := runfile.Read(r)
+	return ProgramDataPointArray(points), err
+}
+
+// LoadProgramDataPointArray reads the run file at path
+func LoadProgramDataPointArray(path string) (ProgramDataPointArray, error) {
+	points, err := runfile.ReadFile(path)
+	return ProgramDataPointArray(points), err
+}
+
+func (d *OvenProgramWorker) runFilePathFor(id string) string {
+	return filepath.Join(d.SavedRunFolder, id+runFileExtension)
+}
+
+// runFilePath returns the file of the run, checking that the id cannot point outside the saved runs folder
+func (d *OvenProgramWorker) runFilePath(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") || id+runFileExtension == workFileName {
+		return "", ErrRunNotFound
+	}
+	path := d.runFilePathFor(id)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", ErrRunNotFound
+	}
+	return path, nil
+}
+
+// RunFilePath returns the path of the raw run file
+func (d *OvenProgramWorker) RunFilePath(id string) (string, error) {
+	return d.runFilePath(id)
+}
+
+// recordingRun returns the run being recorded and the one in the work file, to be resumed
+func (d *OvenProgramWorker) recordingRun() (running, interrupted string) {
+	d.mu.RLock()
+	if d.recording || d.cooldownStop != nil {
+		running = d.runName
+	}
+	d.mu.RUnlock()
+	f, err := os.Open(filepath.Join(d.SavedRunFolder, workFileName))
+	if err != nil {
+		return running, ""
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	if rec, err := reader.Read(); err == nil && len(rec) > 2 {
+		interrupted = rec[2]
+	}
+	return running, interrupted
+}
+
+// lastRunSeconds returns the time from the start of the last point saved in the run, 0 if there is none
+func (d *OvenProgramWorker) lastRunSeconds(id string) float64 {
+	history, err := LoadProgramDataPointArray(d.runFilePathFor(id))
+	if err != nil || len(history) == 0 {
+		return 0
+	}
+	return history[len(history)-1].SecondsFromStart
+}
+
+// GetRuns returns the saved runs, the newest first
+func (d *OvenProgramWorker) GetRuns() ([]RunInfo, error) {
+	entries, err := os.ReadDir(d.SavedRunFolder)
+	if err != nil {
+		return nil, err
+	}
+	running, interrupted := d.recordingRun()
+	runs := make([]RunInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == workFileName || filepath.Ext(entry.Name()) != runFileExtension {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), runFileExtension)
+		info, err := d.runInfo(id, running, interrupted)
+		if err != nil {
+			if d.logger != nil {
+				d.logger.Error("GetRuns: cannot read run", "run", id, "err", err)
+			}
+			continue
+		}
+		runs = append(runs, info)
+	}
+	slices.SortFunc(runs, func(a, b RunInfo) int { return strings.Compare(b.Start+b.ID, a.Start+a.ID) })
+	return runs, nil
+}
+
+// GetRunInfo returns the description of a saved run
+func (d *OvenProgramWorker) GetRunInfo(id string) (RunInfo, error) {
+	running, interrupted := d.recordingRun()
+	return d.runInfo(id, running, interrupted)
+}
+
+func (d *OvenProgramWorker) runInfo(id, running, interrupted string) (RunInfo, error) {
+	path, err := d.runFilePath(id)
+	if err != nil {
+		return RunInfo{}, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return RunInfo{}, err
+	}
+	info := RunInfo{ID: id, Outcome: RunEnded, SizeBytes: stat.Size()}
+	metadata, err := d.GetRunMetadata(id)
+	if err == nil {
+		info.Outcome = metadata.Outcome
+	}
+	switch id {
+	case running:
+		info.Outcome = RunRunning
+	case interrupted:
+		info.Outcome = RunPowerLoss
+	}
+	//an ended run has its summary in the metadata, only the runs in progress and the legacy ones are read
+	if err == nil && id != running && id != interrupted && metadata.Summary != nil && metadata.End != nil &&
+		(metadata.Cooldown == nil || metadata.Cooldown.End != nil) {
+		end := *metadata.End
+		info.ProgramName = metadata.Program.Name
+		info.DurationSeconds = metadata.Summary.DurationSeconds
+		info.PeakTemperature = metadata.Summary.PeakTemperature
+		info.Points = metadata.Summary.Points
+		if metadata.Cooldown != nil {
+			end = *metadata.Cooldown.End
+			info.DurationSeconds = roundTo(end.Sub(metadata.Start).Seconds(), 1)
+			info.Points += metadata.Cooldown.Points
+		}
+		info.Start, info.End = runfile.FormatTime(metadata.Start), runfile.FormatTime(end)
+		return info, nil
+	}
+	history, err := LoadProgramDataPointArray(path)
+	if err != nil {
+		return RunInfo{}, err
+	}
+	info.Points = len(history)
+	if len(history) == 0 {
+		return info, nil
+	}
+	first, last := history[0], history[len(history)-1]
+	info.ProgramName = first.ProgramName
+	info.Start, info.End = first.DateTime, last.DateTime
+	start, errStart := runfile.ParseTime(first.DateTime)
+	end, errEnd := runfile.ParseTime(last.DateTime)
+	if errStart == nil && errEnd == nil {
+		info.DurationSeconds = end.Sub(start).Seconds()
+	} else {
+		info.DurationSeconds = last.SecondsFromStart - first.SecondsFromStart
+	}
+	for _, p := range history {
+		info.PeakTemperature = max(info.PeakTemperature, p.OvenTemperature)
+	}
+	return info, nil
+}
+
+// GetRun returns the data points of a saved run, at most maxPoints if not 0
+func (d *OvenProgramWorker) GetRun(id string, maxPoints int) (ProgramDataPointArray, error) {
+	path, err := d.runFilePath(id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := LoadProgramDataPointArray(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read run %s: %w", id, err)
+	}
+	return history.Downsample(maxPoints), nil
+}
+
+// DeleteRun removes a saved run. The run being recorded cannot be deleted.
+func (d *OvenProgramWorker) DeleteRun(id string) error {
+	path, err := d.runFilePath(id)
+	if err != nil {
+		return err
+	}
+	if running, interrupted := d.recordingRun(); id == running || id == interrupted {
+		return ErrRunInProgress
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	d.removeRunRecords(id)
+	return nil
+}
@@ -0,0 +1,126 @@
+package ovenprograms
+
+import (
+	"slices"
+
+	"github.com/idalmasso/ovencontrol/backend/config"
+)
+
+const (
+	FilterNone   = "none"
+	FilterMedian = "median"
+	FilterEMA    = "ema"
+	FilterKalman = "kalman"
+)
+
+// TemperatureFilter is applied to the oven readings before they are used by the controller
+type TemperatureFilter interface {
+	Filter(value float64) float64
+	Reset()
+}
+
+// NewTemperatureFilter returns the filter selected in the configuration, with defaults for the parameters not set
+func NewTemperatureFilter(c config.Config) TemperatureFilter {
+	f := c.Controller.Filter
+	switch f.Type {
+	case FilterMedian:
+		window := f.Window
+		if window < 1 {
+			window = 5
+		}
+		return &medianFilter{window: window}
+	case FilterEMA:
+		alpha := f.Alpha
+		if alpha <= 0 || alpha > 1 {
+			alpha = 0.3
+		}
+		return &emaFilter{alpha: alpha}
+	case FilterKalman:
+		processNoise, measurementNoise := f.ProcessNoise, f.MeasurementNoise
+		if processNoise <= 0 {
+			processNoise = 0.01
+		}
+		if measurementNoise <= 0 {
+			measurementNoise = 1
+		}
+		return &kalmanFilter{processNoise: processNoise, measurementNoise: measurementNoise}
+	default:
+		return noFilter{}
+	}
+}
+
+type noFilter struct{}
+
+func (noFilter) Filter(value float64) float64 { return value }
+func (noFilter) Reset()                       {}
+
+// medianFilter returns the median of the last window readings, rejecting the single spikes
+type medianFilter struct {
+	window int
+	values []float64
+}
+
+func (f *medianFilter) Filter(value float64) float64 {
+	f.values = append(f.values, value)
+	if len(f.values) > f.window {
+		f.values = f.values[len(f.values)-f.window:]
+	}
+	sorted := slices.Clone(f.values)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func (f *medianFilter) Reset() {
+	f.values = nil
+}
+
+// emaFilter is an exponential moving average, alpha is the weight of the new reading
+type emaFilter struct {
+	alpha       float64
+	value       float64
+	initialized bool
+}
+
+func (f *emaFilter) Filter(value float64) float64 {
+	if !f.initialized {
+		f.value = value
+		f.initialized = true
+		return value
+	}
+	f.value = f.alpha*value + (1-f.alpha)*f.value
+	return f.value
+}
+
+func (f *emaFilter) Reset() {
+	f.initialized = false
+}
+
+// kalmanFilter is a one dimensional Kalman filter with a constant temperature model:
+// processNoise is how much the temperature is expected to change between readings, measurementNoise is the sensor variance
+type kalmanFilter struct {
+	processNoise, measurementNoise float64
+	estimate, errorCovariance      float64
+	initialized                    bool
+}
+
+func (f *kalmanFilter) Filter(value float64) float64 {
+	if !f.initialized {
+		f.estimate = value
+		f.errorCovariance = f.measurementNoise
+		f.initialized = true
+		return value
+	}
+	f.errorCovariance += f.processNoise
+	gain := f.errorCovariance / (f.errorCovariance + f.measurementNoise)
+	f.estimate += gain * (value - f.estimate)
+	f.errorCovariance *= 1 - gain
+	return f.estimate
+}
+
+func (f *kalmanFilter) Reset() {
+	f.initialized = false
+}
@@ -0,0 +1,135 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/idalmasso/ovencontrol/backend/ovenprograms"
+)
+
+const (
+	chartWidth        = 900
+	chartHeight       = 360
+	chartMarginLeft   = 55
+	chartMarginRight  = 55
+	chartMarginTop    = 20
+	chartMarginBottom = 40
+	chartMaxPoints    = 1200
+)
+
+type tick struct {
+	Position float64
+	Label    string
+}
+
+// chart is the SVG chart of a run: the lines are the points attributes of the polylines
+type chart struct {
+	Width, Height               int
+	Left, Right, Top, Bottom    float64
+	Target, Actual, Power       string
+	TimeTicks, TemperatureTicks []tick
+	PowerTicks                  []tick
+}
+
+// timeTickSteps are the possible intervals between the time ticks, in seconds
+var timeTickSteps = []float64{60, 300, 600, 1800, 3600, 7200, 10800, 21600}
+
+func newChart(history ovenprograms.ProgramDataPointArray) chart {
+	c := chart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartMarginLeft,
+		Right:  chartWidth - chartMarginRight,
+		Top:    chartMarginTop,
+		Bottom: chartHeight - chartMarginBottom,
+	}
+	history = history.Downsample(chartMaxPoints)
+	var startSeconds, endSeconds, maxTemperature float64
+	if len(history) > 0 {
+		startSeconds, endSeconds = history[0].SecondsFromStart, history[len(history)-1].SecondsFromStart
+	}
+	duration := math.Max(endSeconds-startSeconds, 60)
+	for _, p := range history {
+		maxTemperature = math.Max(maxTemperature, math.Max(p.DesiredTemperature, p.OvenTemperature))
+	}
+	temperatureStep := 100.0
+	if maxTemperature > 1000 {
+		temperatureStep = 200
+	}
+	topTemperature := math.Max(math.Ceil(maxTemperature/temperatureStep), 1) * temperatureStep
+
+	x := func(seconds float64) float64 {
+		return c.Left + (seconds-startSeconds)/duration*(c.Right-c.Left)
+	}
+	yTemperature := func(t float64) float64 {
+		return c.Bottom - math.Max(t, 0)/topTemperature*(c.Bottom-c.Top)
+	}
+	yPower := func(p float64) float64 {
+		return c.Bottom - p*(c.Bottom-c.Top)
+	}
+	var target, actual, power strings.Builder
+	for _, p := range history {
+		fmt.Fprintf(&target, "%.1f,%.1f ", x(p.SecondsFromStart), yTemperature(p.DesiredTemperature))
+		fmt.Fprintf(&actual, "%.1f,%.1f ", x(p.SecondsFromStart), yTemperature(p.OvenTemperature))
+		fmt.Fprintf(&power, "%.1f,%.1f ", x(p.SecondsFromStart), yPower(p.OvenPercentage))
+	}
+	c.Target, c.Actual, c.Power = target.String(), actual.String(), power.String()
+
+	timeStep := timeTickSteps[len(timeTickSteps)-1]
+	for _, step := range timeTickSteps {
+		if duration/step <= 10 {
+			timeStep = step
+			break
+		}
+	}
+	for seconds := 0.0; seconds <= duration; seconds += timeStep {
+		c.TimeTicks = append(c.TimeTicks, tick{Position: x(startSeconds + seconds), Label: formatClock(seconds)})
+	}
+	for t := 0.0; t <= topTemperature; t += temperatureStep {
+		c.TemperatureTicks = append(c.TemperatureTicks, tick{Position: yTemperature(t), Label: fmt.Sprintf("%.0f", t)})
+	}
+	for p := 0.0; p <= 1; p += 0.25 {
+		c.PowerTicks = append(c.PowerTicks, tick{Position: yPower(p), Label: fmt.Sprintf("%.0f%%", p*100)})
+	}
+	return c
+}
+
+// formatClock returns the seconds as h:mm
+func formatClock(seconds float64) string {
+	minutes := int(math.Round(seconds / 60))
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+// formatDuration returns the seconds as 1h 05m, or 5m 30s under an hour
+func formatDuration(seconds float64) string {
+	if seconds < 3600 {
+		s := int(math.Round(seconds))
+		return fmt.Sprintf("%dm %02ds", s/60, s%60)
+	}
+	minutes := int(math.Round(seconds / 60))
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+func (c chart) PlotWidth() float64 {
+	return c.Right - c.Left
+}
+
+func (c chart) PlotHeight() float64 {
+	return c.Bottom - c.Top
+}
+
+// TemperatureLabelX is where the temperature labels end, on the left of the plot
+func (c chart) TemperatureLabelX() float64 {
+	return c.Left - 6
+}
+
+// PowerLabelX is where the power labels start, on the right of the plot
+func (c chart) PowerLabelX() float64 {
+	return c.Right + 6
+}
+
+// TimeLabelY is the baseline of the time labels, under the plot
+func (c chart) TimeLabelY() float64 {
+	return c.Bottom + 16
+}
@@ -0,0 +1,167 @@
+// Package report renders a saved run as a self-contained HTML page, with the chart drawn in SVG, to be printed.
+package report
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/ovenprograms"
+)
+
+// Data is what is shown in the report of a run
+type Data struct {
+	Info ovenprograms.RunInfo
+	//Program is the definition of the program run, nil if not known
+	Program *ovenprograms.OvenProgram
+	//ProgramSnapshot is true if Program has been saved with the run, false if it is the current definition
+	ProgramSnapshot bool
+	History         ovenprograms.ProgramDataPointArray
+	Segments        []ovenprograms.SegmentStats
+	EnergyKWh       float64
+	Alarms          []commoninterface.Alarm
+	Notes           ovenprograms.RunNotes
+	GeneratedAt     time.Time
+}
+
+// segmentRow is a segment as planned in the program and as it went
+type segmentRow struct {
+	ovenprograms.SegmentStats
+	Planned           bool
+	TargetTemperature float64
+	PlannedSeconds    float64
+}
+
+type page struct {
+	Data
+	Chart       chart
+	SegmentRows []segmentRow
+}
+
+// segmentRows matches the run segments with the program points by name, in order: a resumed run starts from
+// a later point
+func segmentRows(program *ovenprograms.OvenProgram, segments []ovenprograms.SegmentStats) []segmentRow {
+	rows := make([]segmentRow, len(segments))
+	next := 0
+	for i, s := range segments {
+		rows[i].SegmentStats = s
+		if program == nil {
+			continue
+		}
+		for j := next; j < len(program.Points); j++ {
+			if program.Points[j].SegmentName == s.Name {
+				rows[i].Planned = true
+				rows[i].TargetTemperature = program.Points[j].Temperature
+				rows[i].PlannedSeconds = program.Points[j].TimeSeconds()
+				next = j + 1
+				break
+			}
+		}
+	}
+	return rows
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"datetime": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+}).Parse(reportHTML))
+
+// Render writes the report page
+func Render(w io.Writer, data Data) error {
+	return reportTemplate.Execute(w, page{
+		Data:        data,
+		Chart:       newChart(data.History),
+		SegmentRows: segmentRows(data.Program, data.Segments),
+	})
+}
+
+const reportHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Firing report {{.Info.ID}}</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; margin: 20px; color: #222; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+h2 { font-size: 15px; margin-top: 24px; border-bottom: 1px solid #999; }
+table { border-collapse: collapse; margin-top: 6px; }
+th, td { border: 1px solid #bbb; padding: 3px 8px; text-align: right; }
+th { background: #eee; }
+td.text, th.text { text-align: left; }
+dl { display: grid; grid-template-columns: max-content auto; gap: 2px 16px; }
+dt { font-weight: bold; }
+dd { margin: 0; }
+.notes { white-space: pre-wrap; border: 1px solid #bbb; padding: 8px; min-height: 60px; }
+.muted { color: #777; }
+svg text { font-size: 11px; fill: #333; }
+@media print { body { margin: 0; } h2 { break-after: avoid; } table, svg { break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>Firing report: {{if .Info.ProgramName}}{{.Info.ProgramName}}{{else}}{{.Info.ID}}{{end}}</h1>
+<div class="muted">Run {{.Info.ID}}, generated {{datetime .GeneratedAt}}</div>
+
+<h2>Summary</h2>
+<dl>
+<dt>Start</dt><dd>{{.Info.Start}}</dd>
+<dt>End</dt><dd>{{.Info.End}}</dd>
+<dt>Duration</dt><dd>{{duration .Info.DurationSeconds}}</dd>
+<dt>Outcome</dt><dd>{{.Info.Outcome}}</dd>
+<dt>Peak temperature</dt><dd>{{printf "%.1f" .Info.PeakTemperature}} °C</dd>
+<dt>Energy used</dt><dd>{{printf "%.2f" .EnergyKWh}} kWh</dd>
+<dt>Data points</dt><dd>{{.Info.Points}}</dd>
+</dl>
+
+<h2>Chart</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Chart.Width}}" height="{{.Chart.Height}}" viewBox="0 0 {{.Chart.Width}} {{.Chart.Height}}">
+<rect x="{{.Chart.Left}}" y="{{.Chart.Top}}" width="{{.Chart.PlotWidth}}" height="{{.Chart.PlotHeight}}" fill="none" stroke="#999"/>
+{{range .Chart.TemperatureTicks}}<line x1="{{$.Chart.Left}}" x2="{{$.Chart.Right}}" y1="{{.Position}}" y2="{{.Position}}" stroke="#e4e4e4"/>
+<text x="{{$.Chart.TemperatureLabelX}}" y="{{.Position}}" text-anchor="end" dominant-baseline="middle">{{.Label}}</text>
+{{end}}{{range .Chart.PowerTicks}}<text x="{{$.Chart.PowerLabelX}}" y="{{.Position}}" dominant-baseline="middle">{{.Label}}</text>
+{{end}}{{range .Chart.TimeTicks}}<line x1="{{.Position}}" x2="{{.Position}}" y1="{{$.Chart.Top}}" y2="{{$.Chart.Bottom}}" stroke="#e4e4e4"/>
+<text x="{{.Position}}" y="{{$.Chart.TimeLabelY}}" text-anchor="middle">{{.Label}}</text>
+{{end}}<polyline points="{{.Chart.Power}}" fill="none" stroke="#f0a030" stroke-width="1"/>
+<polyline points="{{.Chart.Target}}" fill="none" stroke="#3070d0" stroke-width="1.5" stroke-dasharray="6 3"/>
+<polyline points="{{.Chart.Actual}}" fill="none" stroke="#d03030" stroke-width="1.5"/>
+<text x="{{.Chart.Left}}" y="12">°C</text>
+<text x="{{.Chart.Right}}" y="12" text-anchor="end">power</text>
+</svg>
+<div><span style="color:#3070d0">- - target</span> &nbsp; <span style="color:#d03030">— oven</span> &nbsp; <span style="color:#f0a030">— power</span> &nbsp; <span class="muted">time in h:mm from start</span></div>
+
+<h2>Segments</h2>
+<table>
+<tr><th class="text">Segment</th><th>Target °C</th><th>Planned</th><th>Start</th><th>Actual duration</th><th>Start °C</th><th>End °C</th><th>Max overshoot</th><th>RMS error</th><th>Lag</th><th>Energy kWh</th></tr>
+{{range .SegmentRows}}<tr><td class="text">{{.Name}}</td>
+<td>{{if .Planned}}{{printf "%.0f" .TargetTemperature}}{{else}}-{{end}}</td>
+<td>{{if .Planned}}{{duration .PlannedSeconds}}{{else}}-{{end}}</td>
+<td>{{duration .StartSeconds}}</td><td>{{duration .DurationSeconds}}</td>
+<td>{{printf "%.1f" .StartTemperature}}</td><td>{{printf "%.1f" .EndTemperature}}</td>
+<td>{{printf "%.1f" .MaxOvershoot}}</td><td>{{printf "%.1f" .RMSError}}</td><td>{{duration .TimeLagSeconds}}</td>
+<td>{{printf "%.2f" .EnergyKWh}}</td></tr>
+{{else}}<tr><td class="text" colspan="11">No data points</td></tr>
+{{end}}</table>
+
+<h2>Program</h2>
+{{with .Program}}<div>{{.Name}}, air closed at {{printf "%.0f" .AirCloseAtDegrees}} °C</div>
+<table>
+<tr><th class="text">Segment</th><th>Temperature °C</th><th>Time</th><th class="text">Restart after power loss</th></tr>
+{{range .Points}}<tr><td class="text">{{.SegmentName}}</td><td>{{printf "%.0f" .Temperature}}</td><td>{{duration .TimeSeconds}}</td>
+<td class="text">{{if .RestartFromLastAscendingRamp}}within {{printf "%.0f" .TimeAfterNoRestartMinutes}} min{{else}}no{{end}}</td></tr>
+{{end}}</table>
+{{if not $.ProgramSnapshot}}<div class="muted">Current definition of the program, it may have been edited after the run.</div>{{end}}
+{{else}}<div class="muted">The program definition is not available.</div>
+{{end}}
+<h2>Alarms</h2>
+{{if .Alarms}}<table>
+<tr><th class="text">Time</th><th class="text">Source</th><th class="text">Message</th></tr>
+{{range .Alarms}}<tr><td class="text">{{datetime .Time}}</td><td class="text">{{.Source}}</td><td class="text">{{.Message}}</td></tr>
+{{end}}</table>
+{{else}}<div class="muted">No alarms.</div>
+{{end}}
+<h2>Operator notes</h2>
+<div class="notes">{{.Notes.Notes}}</div>
+{{if not .Notes.UpdatedAt.IsZero}}<div class="muted">Updated {{datetime .Notes.UpdatedAt}}</div>{{end}}
+</body>
+</html>
+`
@@ -0,0 +1,114 @@
+package runfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	fileExtension = ".txt"
+	workFileName  = "work.txt"
+	// BackupExtension is added to the name of a migrated file to keep the original
+	BackupExtension = ".v1"
+)
+
+// IsCurrent returns true if the file starts with the current version record
+func IsCurrent(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(bufio.NewReader(f))
+	reader.FieldsPerRecord = -1
+	rec, err := reader.Read()
+	if err != nil {
+		//an empty file has nothing to migrate
+		return true, nil
+	}
+	return len(rec) > 1 && rec[0] == versionMarker && rec[1] == strconv.Itoa(Version), nil
+}
+
+// Migrate rewrites a run file in the current version, keeping the original with BackupExtension added.
+// It returns false if the file was already in the current version. If a record cannot be read
+// the file is left as it is.
+func Migrate(path string) (bool, error) {
+	if current, err := IsCurrent(path); err != nil || current {
+		return false, err
+	}
+	points, err := ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	sensorCount := 0
+	for _, p := range points {
+		sensorCount = max(sensorCount, len(p.SensorTemperatures))
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return false, err
+	}
+	w := NewWriter(f)
+	err = w.WriteHeader(sensorCount)
+	if err == nil {
+		err = w.Write(points...)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := os.Rename(path, path+BackupExtension); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
+
+// MigrateFolder migrates all the run files in the folder. It returns the files migrated, and the errors of the
+// files that could not be migrated, joined.
+func MigrateFolder(folder string) (migrated []string, err error) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == workFileName || filepath.Ext(entry.Name()) != fileExtension {
+			continue
+		}
+		done, err := Migrate(filepath.Join(folder, entry.Name()))
+		if err != nil {
+			errs = append(errs, &MigrationError{File: entry.Name(), Err: err})
+			continue
+		}
+		if done {
+			migrated = append(migrated, entry.Name())
+		}
+	}
+	return migrated, errors.Join(errs...)
+}
+
+// MigrationError is a file that could not be migrated
+type MigrationError struct {
+	File string
+	Err  error
+}
+
+func (e *MigrationError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
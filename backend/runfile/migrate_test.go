@@ -0,0 +1,134 @@
+package runfile_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/idalmasso/ovencontrol/backend/runfile"
+)
+
+const legacyFile = "Program name,Segment name,Seconds from start,Datetime,Target temperature,Oven temperature,Power percentage,Air closed\n" +
+	"bisque,up,0.0,2024-03-01T10:00:00,20.0,21.0,0.5000,1\n" +
+	"bisque,up,10.0,2024-03-01T10:00:10,21.0,22.0,0.2500,0\n"
+
+func writeFiles(t *testing.T, folder string, files map[string]string) {
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	setLocal(t)
+	tests := []struct {
+		name         string
+		content      string
+		wantMigrated bool
+	}{
+		{name: "legacy", content: legacyFile, wantMigrated: true},
+		{name: "current", content: header + "p,s,0.0,2024-03-01T10:00:00Z,20.0,21.0,0.5000,1,20.0,21.0\n"},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run.txt")
+			writeFiles(t, filepath.Dir(path), map[string]string{"run.txt": tt.content})
+			want, err := runfile.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			migrated, err := runfile.Migrate(path)
+			if err != nil || migrated != tt.wantMigrated {
+				t.Fatalf("Migrate = %v, %v, want %v", migrated, err, tt.wantMigrated)
+			}
+			backup, err := os.ReadFile(path + runfile.BackupExtension)
+			if !tt.wantMigrated {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("backup of a file not migrated: %v", err)
+				}
+				if content, _ := os.ReadFile(path); string(content) != tt.content {
+					t.Errorf("file not migrated changed to %q", content)
+				}
+				return
+			}
+			if string(backup) != tt.content {
+				t.Errorf("backup = %q, want the original file", backup)
+			}
+			if current, err := runfile.IsCurrent(path); err != nil || !current {
+				t.Errorf("IsCurrent after Migrate = %v, %v", current, err)
+			}
+			got, err := runfile.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile after Migrate: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("points after Migrate = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMigrateFolder(t *testing.T) {
+	setLocal(t)
+	folder := t.TempDir()
+	files := map[string]string{
+		"old.txt":     legacyFile,
+		"new.txt":     header,
+		"work.txt":    legacyFile,
+		"old.json":    legacyFile,
+		"broken.txt":  legacyFile + "bisque,up,20.0,2024-03-01T10:00:20,22.0,hot,0.2500,0\n",
+		"already.txt": legacyFile,
+	}
+	writeFiles(t, folder, files)
+	if _, err := runfile.Migrate(filepath.Join(folder, "already.txt")); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	migrated, err := runfile.MigrateFolder(folder)
+	if !slices.Equal(migrated, []string{"old.txt"}) {
+		t.Errorf("migrated = %v, want [old.txt]", migrated)
+	}
+	var migrationErr *runfile.MigrationError
+	if !errors.As(err, &migrationErr) || migrationErr.File != "broken.txt" {
+		t.Fatalf("MigrateFolder error = %v, want broken.txt", err)
+	}
+	var parseErr *runfile.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 4 || parseErr.Column != "Oven temperature" {
+		t.Errorf("MigrateFolder error = %v, want a ParseError at line 4", err)
+	}
+	for _, name := range []string{"work.txt", "old.json", "broken.txt"} {
+		if content, _ := os.ReadFile(filepath.Join(folder, name)); string(content) != files[name] {
+			t.Errorf("%s changed to %q", name, content)
+		}
+		if _, err := os.Stat(filepath.Join(folder, name+runfile.BackupExtension)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("backup of %s: %v", name, err)
+		}
+	}
+
+	migrated, err = runfile.MigrateFolder(folder)
+	if len(migrated) != 0 || !errors.As(err, &migrationErr) {
+		t.Errorf("second MigrateFolder = %v, %v, want nothing migrated", migrated, err)
+	}
+}
+
+func TestAppendToLegacy(t *testing.T) {
+	setLocal(t)
+	folder := t.TempDir()
+	writeFiles(t, folder, map[string]string{"run.txt": legacyFile})
+	path := filepath.Join(folder, "run.txt")
+	if err := runfile.Append(path, testPoints); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	got, err := runfile.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(got) != 4 || !reflect.DeepEqual(got[2:], testPoints) {
+		t.Errorf("points of the resumed run = %+v, want the legacy points and %+v", got, testPoints)
+	}
+}
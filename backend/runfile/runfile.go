@@ -0,0 +1,291 @@
+// Package runfile reads and writes the run data files, where the data points of a firing are saved.
+//
+// A run file is csv. Since version 2 it starts with a version record, followed by the header:
+//
+//	#ovencontrol-run,2
+//	Program name,Segment name,Seconds from start,Datetime,...
+//
+// The times are RFC3339, with the zone, and the air column is 1 when the air is closed.
+// Files without the version record are version 1: the times are local, without the zone.
+// The points of a run resumed into a version 1 file are appended in the current format, without
+// a version record: the reader takes both time formats in any file.
+package runfile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Version is the format written
+	Version = 2
+	// LegacyVersion is the format of the files without a version record
+	LegacyVersion = 1
+
+	versionMarker    = "#ovencontrol-run"
+	legacyTimeFormat = "2006-01-02T15:04:05"
+	// fixedColumns are the columns before the sensor temperatures
+	fixedColumns = 10
+	// legacyMinColumns are the columns of the first version 1 files, before the cold junction was recorded
+	legacyMinColumns = 8
+)
+
+// DataPoint is a sample of a run
+type DataPoint struct {
+	ProgramName             string    `json:"program-name"`
+	SegmentName             string    `json:"segment-name"`
+	SecondsFromStart        float64   `json:"seconds-from-start"`
+	DateTime                string    `json:"datetime"`
+	DesiredTemperature      float64   `json:"desired-temperature"`
+	OvenTemperature         float64   `json:"oven-temperature"`
+	RawTemperature          float64   `json:"raw-oven-temperature"`
+	OvenPercentage          float64   `json:"oven-percentage"`
+	AirClosed               bool      `json:"air-closed"`
+	ColdJunctionTemperature float64   `json:"cold-junction-temperature"`
+	SensorTemperatures      []float64 `json:"sensor-temperatures"`
+}
+
+// ParseError is a record of a run file that cannot be read
+type ParseError struct {
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, %s: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var ErrUnsupportedVersion = errors.New("unsupported run file version")
+
+// FormatTime returns the time as written in the data points
+func FormatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// ParseTime reads a data point time, RFC3339 or, for the version 1 files, local without the zone
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(legacyTimeFormat, s, time.Local)
+}
+
+// Header returns the header record for the number of sensors
+func Header(sensorCount int) []string {
+	s := make([]string, fixedColumns, fixedColumns+sensorCount)
+	s[0] = "Program name"
+	s[1] = "Segment name"
+	s[2] = "Seconds from start"
+	s[3] = "Datetime"
+	s[4] = "Target temperature"
+	s[5] = "Oven temperature"
+	s[6] = "Power percentage"
+	s[7] = "Air closed"
+	s[8] = "Cold junction temperature"
+	s[9] = "Raw oven temperature"
+	for i := 0; i < sensorCount; i++ {
+		s = append(s, fmt.Sprintf("Sensor %d temperature", i+1))
+	}
+	return s
+}
+
+func isHeader(record []string) bool {
+	return len(record) > 0 && record[0] == "Program name"
+}
+
+func record(p DataPoint) []string {
+	s := make([]string, fixedColumns, fixedColumns+len(p.SensorTemperatures))
+	s[0] = p.ProgramName
+	s[1] = p.SegmentName
+	s[2] = strconv.FormatFloat(p.SecondsFromStart, 'f', 1, 64)
+	s[3] = p.DateTime
+	s[4] = strconv.FormatFloat(p.DesiredTemperature, 'f', 1, 64)
+	s[5] = strconv.FormatFloat(p.OvenTemperature, 'f', 1, 64)
+	s[6] = strconv.FormatFloat(p.OvenPercentage, 'f', 4, 64)
+	s[7] = "0"
+	if p.AirClosed {
+		s[7] = "1"
+	}
+	s[8] = strconv.FormatFloat(p.ColdJunctionTemperature, 'f', 1, 64)
+	s[9] = strconv.FormatFloat(p.RawTemperature, 'f', 1, 64)
+	for _, t := range p.SensorTemperatures {
+		s = append(s, strconv.FormatFloat(t, 'f', 1, 64))
+	}
+	return s
+}
+
+// Writer writes a run file
+type Writer struct {
+	w *csv.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: csv.NewWriter(w)}
+}
+
+// WriteHeader writes the version and the header records, at the start of a file
+func (w *Writer) WriteHeader(sensorCount int) error {
+	if err := w.w.Write([]string{versionMarker, strconv.Itoa(Version)}); err != nil {
+		return err
+	}
+	return w.w.Write(Header(sensorCount))
+}
+
+// Write writes the points and flushes them
+func (w *Writer) Write(points ...DataPoint) error {
+	for _, p := range points {
+		if err := w.w.Write(record(p)); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Create creates the run file with the header for the number of sensors. An existing file is kept as it is,
+// with its header if not empty, so that a resumed run continues in it.
+func Create(path string, sensorCount int) error {
+	return appendPoints(path, sensorCount, nil)
+}
+
+// Append adds the points to the run file, creating it with the header if new or empty
+func Append(path string, points []DataPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	return appendPoints(path, len(points[0].SensorTemperatures), points)
+}
+
+func appendPoints(path string, sensorCount int, points []DataPoint) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	w := NewWriter(f)
+	if info.Size() == 0 {
+		if err := w.WriteHeader(sensorCount); err != nil {
+			return err
+		}
+	}
+	if err := w.Write(points...); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Read reads all the points of a run file, of any version
+func Read(r io.Reader) ([]DataPoint, error) {
+	reader := csv.NewReader(r)
+	//the sensor columns depend on the oven, and old runs have less columns
+	reader.FieldsPerRecord = -1
+	version := LegacyVersion
+	points := make([]DataPoint, 0)
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return points, nil
+		}
+		if err != nil {
+			//the csv errors have their own line
+			return points, err
+		}
+		line, _ := reader.FieldPos(0)
+		switch {
+		case rec[0] == versionMarker:
+			if len(rec) < 2 {
+				return points, &ParseError{Line: line, Err: ErrUnsupportedVersion}
+			}
+			if version, err = strconv.Atoi(rec[1]); err != nil || version < LegacyVersion || version > Version {
+				return points, &ParseError{Line: line, Err: fmt.Errorf("%w %s", ErrUnsupportedVersion, rec[1])}
+			}
+		case isHeader(rec):
+		default:
+			p, err := parseRecord(rec, version)
+			if err != nil {
+				err.Line = line
+				return points, err
+			}
+			points = append(points, p)
+		}
+	}
+}
+
+// ReadFile reads all the points of the run file at path
+func ReadFile(path string) ([]DataPoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+func parseRecord(rec []string, version int) (DataPoint, *ParseError) {
+	minColumns := fixedColumns
+	if version == LegacyVersion {
+		minColumns = legacyMinColumns
+	}
+	if len(rec) < minColumns {
+		return DataPoint{}, &ParseError{Err: fmt.Errorf("%d columns, at least %d expected", len(rec), minColumns)}
+	}
+	header := Header(len(rec) - min(len(rec), fixedColumns))
+	var parseErr *ParseError
+	float := func(column int) float64 {
+		if parseErr != nil || column >= len(rec) {
+			return 0
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[column]), 64)
+		if err != nil {
+			parseErr = &ParseError{Column: header[column], Err: err}
+		}
+		return v
+	}
+	p := DataPoint{
+		ProgramName:             rec[0],
+		SegmentName:             rec[1],
+		SecondsFromStart:        float(2),
+		DesiredTemperature:      float(4),
+		OvenTemperature:         float(5),
+		OvenPercentage:          float(6),
+		ColdJunctionTemperature: float(8),
+		RawTemperature:          float(9),
+	}
+	for column := fixedColumns; column < len(rec); column++ {
+		p.SensorTemperatures = append(p.SensorTemperatures, float(column))
+	}
+	if parseErr != nil {
+		return DataPoint{}, parseErr
+	}
+	switch strings.TrimSpace(rec[7]) {
+	case "1":
+		p.AirClosed = true
+	case "0":
+	default:
+		return DataPoint{}, &ParseError{Column: header[7], Err: fmt.Errorf("invalid value %q", rec[7])}
+	}
+	t, err := ParseTime(rec[3])
+	if err != nil {
+		return DataPoint{}, &ParseError{Column: header[3], Err: err}
+	}
+	p.DateTime = FormatTime(t)
+	return p, nil
+}
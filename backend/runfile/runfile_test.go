@@ -0,0 +1,135 @@
+package runfile_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idalmasso/ovencontrol/backend/runfile"
+)
+
+var testPoints = []runfile.DataPoint{
+	{ProgramName: "bisque", SegmentName: "up", SecondsFromStart: 0, DateTime: "2024-03-01T10:00:00Z",
+		DesiredTemperature: 20, OvenTemperature: 21.5, RawTemperature: 21.7, OvenPercentage: 0.25,
+		ColdJunctionTemperature: 22.1, SensorTemperatures: []float64{21.5, 21.9}},
+	{ProgramName: "bisque", SegmentName: "up", SecondsFromStart: 10.5, DateTime: "2024-03-01T10:00:10+01:00",
+		DesiredTemperature: 21, OvenTemperature: -3.5, RawTemperature: -3.5, OvenPercentage: 1, AirClosed: true,
+		ColdJunctionTemperature: 22.1, SensorTemperatures: []float64{-3.5, 0}},
+}
+
+func TestRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	w := runfile.NewWriter(&b)
+	if err := w.WriteHeader(2); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	if err := w.Write(testPoints...); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "#ovencontrol-run,2\nProgram name,") {
+		t.Errorf("file starts with %q, want the version and the header records", b.String()[:30])
+	}
+	got, err := runfile.Read(&b)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !reflect.DeepEqual(got, testPoints) {
+		t.Errorf("Read = %+v, want %+v", got, testPoints)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.txt")
+	if err := runfile.Create(path, 2); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, p := range testPoints {
+		if err := runfile.Append(path, []runfile.DataPoint{p}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	//a resumed run keeps the file
+	if err := runfile.Create(path, 2); err != nil {
+		t.Fatalf("Create of an existing file: %v", err)
+	}
+	got, err := runfile.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !reflect.DeepEqual(got, testPoints) {
+		t.Errorf("ReadFile = %+v, want %+v", got, testPoints)
+	}
+}
+
+const header = "#ovencontrol-run,2\nProgram name,Segment name,Seconds from start,Datetime,Target temperature," +
+	"Oven temperature,Power percentage,Air closed,Cold junction temperature,Raw oven temperature\n"
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		wantLine   int
+		wantColumn string
+		wantErr    error
+	}{
+		{name: "bad number", file: header + "p,s,0.0,2024-03-01T10:00:00Z,20.0,21.0,0.5,0,20.0,21.0\n" +
+			"p,s,1.0,2024-03-01T10:00:01Z,20.0,hot,0.5,0,20.0,21.0\n",
+			wantLine: 4, wantColumn: "Oven temperature"},
+		{name: "bad sensor", file: header + "p,s,0.0,2024-03-01T10:00:00Z,20.0,21.0,0.5,0,20.0,21.0,x\n",
+			wantLine: 3, wantColumn: "Sensor 1 temperature"},
+		{name: "bad air", file: header + "p,s,0.0,2024-03-01T10:00:00Z,20.0,21.0,0.5,2,20.0,21.0\n",
+			wantLine: 3, wantColumn: "Air closed"},
+		{name: "bad time", file: header + "p,s,0.0,yesterday,20.0,21.0,0.5,0,20.0,21.0\n",
+			wantLine: 3, wantColumn: "Datetime"},
+		{name: "few columns", file: header + "p,s,0.0,2024-03-01T10:00:00Z,20.0,21.0,0.5,0\n",
+			wantLine: 3},
+		{name: "unknown version", file: "#ovencontrol-run,3\n", wantLine: 1, wantErr: runfile.ErrUnsupportedVersion},
+		{name: "version missing", file: "#ovencontrol-run\n", wantLine: 1, wantErr: runfile.ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runfile.Read(strings.NewReader(tt.file))
+			var parseErr *runfile.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Read error = %v, want a ParseError", err)
+			}
+			if parseErr.Line != tt.wantLine || parseErr.Column != tt.wantColumn {
+				t.Errorf("error at line %d, column %q, want line %d, column %q", parseErr.Line, parseErr.Column, tt.wantLine, tt.wantColumn)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Read error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// setLocal sets the zone of the version 1 times for the test
+func setLocal(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("CET", 3600)
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestReadLegacy(t *testing.T) {
+	setLocal(t)
+	file := "Program name,Segment name,Seconds from start,Datetime,Target temperature,Oven temperature,Power percentage,Air closed\n" +
+		"bisque,up,0.0,2024-03-01T10:00:00,20.0,21.0,0.5000,1\n" +
+		"bisque,up,10.0,2024-03-01T10:00:10,21.0,22.0,0.2500,0,23.5,22.5\n"
+	want := []runfile.DataPoint{
+		{ProgramName: "bisque", SegmentName: "up", SecondsFromStart: 0, DateTime: "2024-03-01T10:00:00+01:00",
+			DesiredTemperature: 20, OvenTemperature: 21, OvenPercentage: 0.5, AirClosed: true},
+		{ProgramName: "bisque", SegmentName: "up", SecondsFromStart: 10, DateTime: "2024-03-01T10:00:10+01:00",
+			DesiredTemperature: 21, OvenTemperature: 22, OvenPercentage: 0.25, ColdJunctionTemperature: 23.5, RawTemperature: 22.5},
+	}
+	got, err := runfile.Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read = %+v, want %+v", got, want)
+	}
+}
@@ -3,12 +3,16 @@ package server
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/eventlog"
 )
 
 func (s *MachineServer) openAir(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("openAir called")
 	err := s.machine.OpenAir()
 	if err != nil {
+		s.ovenProgramWorker.LogEvent(eventlog.Error, eventlog.CategoryAir, "cannot open the air manually", map[string]any{"error": err.Error()})
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(struct {
 			Error string `json:"error"`
@@ -16,6 +20,7 @@ func (s *MachineServer) openAir(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
+	s.ovenProgramWorker.LogEvent(eventlog.Info, eventlog.CategoryAir, "air opened manually", nil)
 	w.WriteHeader(http.StatusOK)
 
 }
@@ -24,6 +29,7 @@ func (s *MachineServer) closeAir(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("closeAir called")
 	err := s.machine.CloseAir()
 	if err != nil {
+		s.ovenProgramWorker.LogEvent(eventlog.Error, eventlog.CategoryAir, "cannot close the air manually", map[string]any{"error": err.Error()})
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(struct {
 			Error string `json:"error"`
@@ -31,6 +37,13 @@ func (s *MachineServer) closeAir(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
+	s.ovenProgramWorker.LogEvent(eventlog.Info, eventlog.CategoryAir, "air closed manually", nil)
 	w.WriteHeader(http.StatusOK)
 
 }
+
+func (s *MachineServer) airStatus(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(struct {
+		Position commoninterface.AirPosition `json:"position"`
+	}{Position: s.machine.GetAirPosition()})
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/idalmasso/ovencontrol/backend/auth"
+	"github.com/idalmasso/ovencontrol/backend/eventlog"
+)
+
+// sessionCookie holds the session token for the browsers, other clients can send it as a bearer token
+const sessionCookie = "oven_session"
+
+type userContextKey struct{}
+
+// requestUser returns the user logged in the request, if any
+func requestUser(r *http.Request) (auth.User, bool) {
+	u, ok := r.Context().Value(userContextKey{}).(auth.User)
+	return u, ok
+}
+
+// requestToken returns the session token of the request, from the Authorization header or from the cookie
+func requestToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// authenticate adds to the request context the user of a valid session token
+func (s *MachineServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := requestToken(r); s.authEnabled && token != "" {
+			if session, ok := s.sessions.Get(token); ok {
+				if u, err := s.users.Get(session.Username); err == nil {
+					r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, u))
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireRole answers 401 without a login and 403 if the user role is not enough. It allows everything if the
+// authentication is disabled.
+func (s *MachineServer) requireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.authEnabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+			u, ok := requestUser(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="ovencontrol"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(struct{ Error string }{Error: "login required"})
+				return
+			}
+			if !u.Role.Allows(role) {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(struct{ Error string }{Error: "the role " + string(u.Role) + " cannot do this, " + string(role) + " needed"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authStatus is the answer of the login and of the current user request
+type authStatus struct {
+	Enabled bool       `json:"enabled"`
+	User    *auth.User `json:"user,omitempty"`
+	Token   string     `json:"token,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// peerAddress is the IP of the connection, not the one the client can claim in the forwarded headers
+func peerAddress(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func (s *MachineServer) login(w http.ResponseWriter, r *http.Request) {
+	var credentials struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	address := peerAddress(r)
+	if wait := s.loginThrottle.Start(address); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: "too many failed logins, retry later"})
+		return
+	}
+	u, err := s.users.Authenticate(credentials.Username, credentials.Password)
+	s.loginThrottle.Done(address, err == nil)
+	if err != nil {
+		s.ovenProgramWorker.LogEvent(eventlog.Warning, eventlog.CategoryAuth, "login failed",
+			map[string]any{"username": credentials.Username, "address": address})
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			w.WriteHeader(http.StatusUnauthorized)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	token, session, err := s.sessions.Create(u.Username)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	s.ovenProgramWorker.LogEvent(eventlog.Info, eventlog.CategoryAuth, "login",
+		map[string]any{"username": u.Username, "address": address})
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/api",
+		Expires:  session.Expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(authStatus{Enabled: s.authEnabled, User: &u, Token: token, Expires: &session.Expires})
+}
+
+func (s *MachineServer) logout(w http.ResponseWriter, r *http.Request) {
+	if token := requestToken(r); token != "" {
+		s.sessions.Delete(token)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/api", MaxAge: -1, HttpOnly: true})
+	w.WriteHeader(http.StatusOK)
+}
+
+// getCurrentUser tells if a login is needed and who is logged in
+func (s *MachineServer) getCurrentUser(w http.ResponseWriter, r *http.Request) {
+	status := authStatus{Enabled: s.authEnabled}
+	if u, ok := requestUser(r); ok {
+		status.User = &u
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// changePassword changes the password of the user logged in, that must send the current one too
+func (s *MachineServer) changePassword(w http.ResponseWriter, r *http.Request) {
+	u, ok := requestUser(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: "no user logged in"})
+		return
+	}
+	var request struct {
+		CurrentPassword string `json:"current-password"`
+		Password        string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	if _, err := s.users.Authenticate(u.Username, request.CurrentPassword); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	if err := s.users.SetPassword(u.Username, request.Password); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	s.sessions.DeleteUser(u.Username, requestToken(r))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *MachineServer) getUsers(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.users.Users())
+}
+
+func (s *MachineServer) addUser(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Username string    `json:"username"`
+		Password string    `json:"password"`
+		Role     auth.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	u, err := s.users.Add(request.Username, request.Password, request.Role)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	s.ovenProgramWorker.LogEvent(eventlog.Info, eventlog.CategoryAuth, "user added",
+		map[string]any{"username": u.Username, "role": u.Role, "by": requestUsername(r)})
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(u)
+}
+
+// updateUser changes the role and/or the password of a user. A new password ends the sessions of the user.
+func (s *MachineServer) updateUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	var request struct {
+		Password string    `json:"password"`
+		Role     auth.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	u, err := s.users.Update(username, request.Password, request.Role)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	if request.Password != "" {
+		s.sessions.DeleteUser(username, "")
+	}
+	s.ovenProgramWorker.LogEvent(eventlog.Info, eventlog.CategoryAuth, "user updated",
+		map[string]any{"username": u.Username, "role": u.Role, "password-changed": request.Password != "", "by": requestUsername(r)})
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+func (s *MachineServer) deleteUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	if err := s.users.Delete(username); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	s.sessions.DeleteUser(username, "")
+	s.ovenProgramWorker.LogEvent(eventlog.Info, eventlog.CategoryAuth, "user deleted",
+		map[string]any{"username": username, "by": requestUsername(r)})
+	w.WriteHeader(http.StatusOK)
+}
+
+// requestUsername is the user of the request for the event log, empty without a login
+func requestUsername(r *http.Request) string {
+	u, _ := requestUser(r)
+	return u.Username
+}
+
+// writeUserError maps the errors of the user store to the status codes
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, auth.ErrUserExists), errors.Is(err, auth.ErrLastAdmin):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrPasswordTooShort),
+		errors.Is(err, auth.ErrUnknownRole):
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+}
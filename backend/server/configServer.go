@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/eventlog"
 )
 
 func (s *MachineServer) updateConfig(w http.ResponseWriter, r *http.Request) {
@@ -17,15 +18,27 @@ func (s *MachineServer) updateConfig(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(struct{ Err error }{Err: err})
 		return
 	}
-	s.configuration = &config
-	if err := s.configuration.SaveToFile("configuration.yaml"); err != nil {
+	if err := config.Hardware.Validate(); err != nil {
+		s.logger.LogAttrs(r.Context(), slog.LevelError, "updateConfig error", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	if err := config.SaveToFile(s.configFile); err != nil {
 		s.logger.LogAttrs(r.Context(), slog.LevelError, "updateConfig error", slog.String("error", err.Error()))
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(struct{ Err error }{Err: err})
 		return
 	}
+	s.setConfiguration(config)
 
-	s.updateMachineFromConfig()
+	//the worker changes the hardware settings only when nothing is running
+	s.ovenProgramWorker.UpdateConfig(*s.configuration)
+	if s.ovenProgramWorker.IsWorking() {
+		s.ovenProgramWorker.LogEvent(eventlog.Warning, eventlog.CategoryConfig, "configuration edited during a run, applied when it ends", nil)
+	} else {
+		s.ovenProgramWorker.LogEvent(eventlog.Info, eventlog.CategoryConfig, "configuration edited", nil)
+	}
 
 	w.WriteHeader(http.StatusOK)
 
@@ -34,5 +47,5 @@ func (s *MachineServer) updateConfig(w http.ResponseWriter, r *http.Request) {
 func (s *MachineServer) getConfig(w http.ResponseWriter, r *http.Request) {
 	s.logger.DebugContext(r.Context(), "getConfig called")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(s.configuration)
+	json.NewEncoder(w).Encode(s.fileConfiguration)
 }
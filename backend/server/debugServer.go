@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/idalmasso/ovencontrol/backend/auth"
+	"github.com/idalmasso/ovencontrol/backend/dummyinterface"
+)
+
+// faultInjector is a simulated machine where failures can be injected, to test the safety logic
+type faultInjector interface {
+	InjectFault(f dummyinterface.Fault) (int, error)
+	ClearFault(id int) bool
+	ClearFaults()
+	GetFaults() []dummyinterface.ScheduledFault
+}
+
+// debugRoutes adds the fault injection routes, only if the machine supports them
+func (s *MachineServer) debugRoutes(router chi.Router) {
+	injector, ok := s.machine.(faultInjector)
+	if !ok {
+		return
+	}
+	router.Route("/debug/faults", func(r chi.Router) {
+		r.Use(s.requireRole(auth.Operator))
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(injector.GetFaults())
+		})
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			var fault dummyinterface.Fault
+			if err := json.NewDecoder(r.Body).Decode(&fault); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct {
+					Error string `json:"error"`
+				}{Error: err.Error()})
+				return
+			}
+			id, err := injector.InjectFault(fault)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct {
+					Error string `json:"error"`
+				}{Error: err.Error()})
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(struct {
+				ID int `json:"id"`
+			}{ID: id})
+		})
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			injector.ClearFaults()
+			w.WriteHeader(http.StatusOK)
+		})
+		r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(chi.URLParam(r, "id"))
+			if err != nil || !injector.ClearFault(id) {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(struct {
+					Error string `json:"error"`
+				}{Error: "fault not found"})
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+)
+
+func (s *MachineServer) getDiagnostics(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getDiagnostics called")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Report *commoninterface.DiagnosticsReport `json:"report"`
+	}{Report: s.ovenProgramWorker.GetDiagnostics()})
+}
+
+// runDiagnostics runs the self test, with the power pulse if the query has power-pulse=true
+func (s *MachineServer) runDiagnostics(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("runDiagnostics called")
+	report, err := s.ovenProgramWorker.RunDiagnostics(r.URL.Query().Get("power-pulse") == "true")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Report *commoninterface.DiagnosticsReport `json:"report"`
+	}{Report: &report})
+}
+
+// diagnosticsBlockStart writes the error and returns true if the last diagnostics failed,
+// unless the request has override=true
+func (s *MachineServer) diagnosticsBlockStart(w http.ResponseWriter, r *http.Request) bool {
+	if !s.ovenProgramWorker.DiagnosticsFailed() {
+		return false
+	}
+	if r.URL.Query().Get("override") == "true" {
+		s.logger.Info("Diagnostics failed, program start forced by override")
+		return false
+	}
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(struct {
+		Error  string
+		Report *commoninterface.DiagnosticsReport `json:"report"`
+	}{Error: "Diagnostics failed, start with override=true to ignore", Report: s.ovenProgramWorker.GetDiagnostics()})
+	return true
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/idalmasso/ovencontrol/backend/eventlog"
+)
+
+// eventQuery reads the query parameters of the event logs: from and to (RFC3339), severity (the minimum one),
+// category and limit (the last entries)
+func eventQuery(r *http.Request) (eventlog.Query, error) {
+	var q eventlog.Query
+	var err error
+	values := r.URL.Query()
+	if v := values.Get("from"); v != "" {
+		if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return q, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if v := values.Get("to"); v != "" {
+		if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return q, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if v := values.Get("severity"); v != "" {
+		if q.MinSeverity, err = eventlog.ParseSeverity(v); err != nil {
+			return q, err
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			return q, fmt.Errorf("invalid limit %s", v)
+		}
+	}
+	q.Category = values.Get("category")
+	return q, nil
+}
+
+func (s *MachineServer) getEvents(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getEvents called")
+	q, err := eventQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	entries, err := s.ovenProgramWorker.GetEvents(q)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (s *MachineServer) getRunEvents(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getRunEvents called")
+	q, err := eventQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	entries, err := s.ovenProgramWorker.GetRunEvents(chi.URLParam(r, "runID"), q)
+	if err != nil {
+		s.writeRunError(w, r, "getRunEvents", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
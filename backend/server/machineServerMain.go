@@ -13,7 +13,10 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/httplog/v2"
+	"github.com/idalmasso/ovencontrol/backend/auth"
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
 	"github.com/idalmasso/ovencontrol/backend/config"
+	"github.com/idalmasso/ovencontrol/backend/events"
 	"github.com/idalmasso/ovencontrol/backend/ovenprograms"
 )
 
@@ -21,18 +24,58 @@ type controllerMachine interface {
 	temperatureReader
 	ovenprograms.Oven
 	InitConfig(c config.Config)
+	GetAlarms() []commoninterface.Alarm
+	GetAirPosition() commoninterface.AirPosition
 	Terminate()
 }
 
+// Machine is the controller driven by the server, the raspberry one or the simulator
+type Machine = controllerMachine
+
 // PiServer
 type MachineServer struct {
 	ovenProgramManager ovenprograms.OvenProgramManager
-	configuration      *config.Config
-	initialized        bool
-	Router             chi.Router
-	machine            controllerMachine
-	ovenProgramWorker  *ovenprograms.OvenProgramWorker
-	logger             *httplog.Logger
+	//configuration is the one in use, with the data directories of the server options
+	configuration *config.Config
+	//fileConfiguration is the one in the configuration file, returned and saved by the configuration routes
+	fileConfiguration *config.Config
+	initialized       bool
+	Router            chi.Router
+	machine           controllerMachine
+	ovenProgramWorker *ovenprograms.OvenProgramWorker
+	logger            *httplog.Logger
+	configFile        string
+	listenAddress     string
+	programsDirectory string
+	runsDirectory     string
+	events            *events.Hub
+	authEnabled       bool
+	users             *auth.Store
+	sessions          *auth.Sessions
+	loginThrottle     *auth.LoginThrottle
+}
+
+// WithConfigFile sets the configuration file read at Init and written by the configuration updates
+func WithConfigFile(path string) func(*MachineServer) {
+	return func(s *MachineServer) {
+		s.configFile = path
+	}
+}
+
+// WithListenAddress sets the address to listen on, instead of the port in the configuration
+func WithListenAddress(address string) func(*MachineServer) {
+	return func(s *MachineServer) {
+		s.listenAddress = address
+	}
+}
+
+// WithDataDirectories sets the folders of the programs and of the runs, instead of the ones in the configuration.
+// Empty values keep the configuration.
+func WithDataDirectories(programsDirectory, runsDirectory string) func(*MachineServer) {
+	return func(s *MachineServer) {
+		s.programsDirectory = programsDirectory
+		s.runsDirectory = runsDirectory
+	}
 }
 
 // ListenAndServe is the main server procedure that only wraps http.ListenAndServe
@@ -42,7 +85,12 @@ func (s *MachineServer) ListenAndServe() {
 		panic("Server not initialized")
 	}
 	defer s.machine.Terminate()
-	if err := http.ListenAndServe(":"+strconv.Itoa(s.configuration.Server.Port), s.Router); err != nil {
+	address := s.listenAddress
+	if address == "" {
+		address = ":" + strconv.Itoa(s.configuration.Server.Port)
+	}
+	s.logger.Info("Listening", "address", address)
+	if err := http.ListenAndServe(address, s.Router); err != nil {
 
 		panic("Cannot listen on server: " + err.Error())
 	}
@@ -52,11 +100,12 @@ func (s *MachineServer) ListenAndServe() {
 func (s *MachineServer) Init(machine controllerMachine, logger *httplog.Logger) {
 	// Logger
 	s.logger = logger
-	s.configuration = &config.Config{}
-	if err := s.configuration.ReadFromFile("configuration.yaml"); err != nil {
+	var fileConfiguration config.Config
+	if err := fileConfiguration.ReadFromFile(s.configFile); err != nil {
 		s.logger.Error("Error", "error", err)
 		panic("cannot read configuration file")
 	}
+	s.setConfiguration(fileConfiguration)
 
 	var err error
 	s.ovenProgramManager, err = ovenprograms.NewOvenProgramManager(s.configuration.Server.OvenProgramFolder)
@@ -68,12 +117,26 @@ func (s *MachineServer) Init(machine controllerMachine, logger *httplog.Logger)
 	s.machine = machine
 
 	s.updateMachineFromConfig()
-	s.ovenProgramWorker = ovenprograms.NewOvenProgramWorker(s.machine, *s.configuration, s.ovenProgramManager, s.logger)
+	s.events = events.NewHub(0)
+	s.ovenProgramWorker = ovenprograms.NewOvenProgramWorker(s.machine, *s.configuration, s.ovenProgramManager, s.logger,
+		ovenprograms.WithEventPublisher(s.events))
+	if notifier, ok := s.machine.(alarmNotifier); ok {
+		worker := s.ovenProgramWorker
+		notifier.SetAlarmHandler(func(a commoninterface.Alarm) {
+			s.events.Publish(events.Alarm, a)
+			worker.RecordAlarm(a)
+		})
+	}
+	s.initAuth()
 	s.Router = chi.NewRouter()
+	var allowOrigin func(r *http.Request, origin string) bool
+	if len(s.configuration.Auth.AllowedOrigins) == 0 {
+		//the cors handler allows every origin when none is set
+		allowOrigin = sameOrigin
+	}
 	s.Router.Use(cors.Handler(cors.Options{
-		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
-		AllowedOrigins: []string{"https://*", "http://*"},
-		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
+		AllowedOrigins:   s.configuration.Auth.AllowedOrigins,
+		AllowOriginFunc:  allowOrigin,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
@@ -83,70 +146,183 @@ func (s *MachineServer) Init(machine controllerMachine, logger *httplog.Logger)
 	s.Router.Use(httplog.RequestLogger(s.logger))
 	s.Router.Use(middleware.Heartbeat("/ping"))
 	s.Router.Use(middleware.RequestID)
-	s.Router.Use(middleware.RealIP)
+	//no RealIP: the forwarded headers are set by the clients, and the login throttle needs the real peer
 	s.Router.Use(middleware.Logger)
 	s.Router.Use(middleware.Recoverer)
-	s.Router.Use(middleware.Timeout(60 * time.Second))
+	s.Router.Use(timeoutExceptStream(60 * time.Second))
 
 	s.FileServer(s.Router.(*chi.Mux), s.configuration.Server.DistributionDirectory)
 	s.Router.Route("/api", func(router chi.Router) {
-		router.Route("/power-off", func(router chi.Router) {
-			router.Post("/", s.powerOff)
+		router.Use(s.authenticate)
+		router.Route("/auth", func(r chi.Router) {
+			r.Post("/login", s.login)
+			r.Post("/logout", s.logout)
+			r.Get("/me", s.getCurrentUser)
+			r.With(s.requireRole(auth.Viewer)).Put("/password", s.changePassword)
 		})
-		router.Route("/processes", func(processRouter chi.Router) {
-			processRouter.Route("/set-power-one-minute", func(r chi.Router) {
-				r.Post("/", s.setPowerOneMinute)
-			})
-			processRouter.Route("/open-air", func(r chi.Router) {
-				r.Post("/", s.openAir)
-			})
-			processRouter.Route("/close-air", func(r chi.Router) {
-				r.Post("/", s.closeAir)
-			})
-			processRouter.Route("/get-temperature", func(r chi.Router) {
-				r.Get("/", s.getTemperature)
-			})
-			processRouter.Route("/get-temperatures-process", func(r chi.Router) {
-				r.Get("/", s.getTemperaturesProcess)
-			})
-			processRouter.Route("/is-working", func(r chi.Router) {
-				r.Get("/", s.isWorking)
-			})
-			processRouter.Route("/test-ramp", func(r chi.Router) {
-				r.Post("/", s.testRamp)
-			})
-			processRouter.Route("/get-actual-process-data", func(r chi.Router) {
-				r.Get("/", s.getAllDataActualWork)
+		router.Group(func(router chi.Router) {
+			router.Use(s.requireRole(auth.Viewer))
+			operator := s.requireRole(auth.Operator)
+			admin := s.requireRole(auth.Admin)
+			s.debugRoutes(router)
+			router.Route("/power-off", func(router chi.Router) {
+				router.With(admin).Post("/", s.powerOff)
 			})
-			processRouter.Route("/start-process/{programName}", func(r chi.Router) {
-				r.Post("/", s.startProgram)
-			})
-			processRouter.Route("/stop-process", func(r chi.Router) {
-				r.Post("/", s.stopProgram)
+			router.Route("/users", func(r chi.Router) {
+				r.Use(admin)
+				r.Get("/", s.getUsers)
+				r.Post("/", s.addUser)
+				r.Put("/{username}", s.updateUser)
+				r.Delete("/{username}", s.deleteUser)
 			})
+			router.Route("/processes", func(processRouter chi.Router) {
+				processRouter.Route("/set-power-one-minute", func(r chi.Router) {
+					r.With(operator).Post("/", s.setPowerOneMinute)
+				})
+				processRouter.Route("/open-air", func(r chi.Router) {
+					r.With(operator).Post("/", s.openAir)
+				})
+				processRouter.Route("/close-air", func(r chi.Router) {
+					r.With(operator).Post("/", s.closeAir)
+				})
+				processRouter.Route("/stream", func(r chi.Router) {
+					r.Get("/", s.stream)
+				})
+				processRouter.Route("/diagnostics", func(r chi.Router) {
+					r.Get("/", s.getDiagnostics)
+					r.With(operator).Post("/", s.runDiagnostics)
+				})
+				processRouter.Route("/indicator", func(r chi.Router) {
+					r.Get("/", s.getIndicator)
+				})
+				processRouter.Route("/acknowledge", func(r chi.Router) {
+					r.With(operator).Post("/", s.acknowledge)
+				})
+				processRouter.Route("/interlocks", func(r chi.Router) {
+					r.Get("/", s.getInterlocks)
+				})
+				processRouter.Route("/air-status", func(r chi.Router) {
+					r.Get("/", s.airStatus)
+				})
+				processRouter.Route("/get-temperature", func(r chi.Router) {
+					r.Get("/", s.getTemperature)
+				})
+				processRouter.Route("/get-temperatures-process", func(r chi.Router) {
+					r.Get("/", s.getTemperaturesProcess)
+				})
+				processRouter.Route("/get-alarms", func(r chi.Router) {
+					r.Get("/", s.getAlarms)
+				})
+				processRouter.Route("/is-working", func(r chi.Router) {
+					r.Get("/", s.isWorking)
+				})
+				processRouter.Route("/test-ramp", func(r chi.Router) {
+					r.With(operator).Post("/", s.testRamp)
+				})
+				processRouter.Route("/get-actual-process-data", func(r chi.Router) {
+					r.Get("/", s.getAllDataActualWork)
+				})
+				processRouter.Route("/process-data", func(r chi.Router) {
+					r.Get("/", s.getProcessData)
+				})
+				processRouter.Route("/start-process/{programName}", func(r chi.Router) {
+					r.With(operator).Post("/", s.startProgram)
+				})
+				processRouter.Route("/stop-process", func(r chi.Router) {
+					r.With(operator).Post("/", s.stopProgram)
+				})
 
-		})
-		router.Route("/configuration", func(configRouter chi.Router) {
-			configRouter.Route("/programs", func(r chi.Router) {
-				r.Get("/", s.getPrograms)
-				r.Post("/", s.addUpdateProgram)
-				r.Get("/{programName}", s.getProgram)
-				r.Delete("/{programName}", s.deleteProgram)
 			})
-			configRouter.Route("/oven-config", func(r chi.Router) {
-				r.Get("/", s.getConfig)
-				r.Post("/", s.updateConfig)
+			router.Route("/events", func(r chi.Router) {
+				r.Get("/", s.getEvents)
 			})
-			configRouter.Route("/move-runs-usb", func(r chi.Router) {
-				r.Post("/", s.moveAllRunsToUsb)
+			router.Route("/runs", func(r chi.Router) {
+				r.Get("/", s.getRuns)
+				r.Get("/compare", s.compareRuns)
+				r.Get("/{runID}", s.getRun)
+				r.Get("/{runID}/download", s.downloadRun)
+				r.Get("/{runID}/report", s.getRunReport)
+				r.Get("/{runID}/metadata", s.getRunMetadata)
+				r.Get("/{runID}/alarms", s.getRunAlarms)
+				r.Get("/{runID}/events", s.getRunEvents)
+				r.Get("/{runID}/notes", s.getRunNotes)
+				r.With(operator).Put("/{runID}/notes", s.setRunNotes)
+				r.With(admin).Delete("/{runID}", s.deleteRun)
 			})
+			router.Route("/configuration", func(configRouter chi.Router) {
+				configRouter.Route("/programs", func(r chi.Router) {
+					r.Get("/", s.getPrograms)
+					r.With(admin).Post("/", s.addUpdateProgram)
+					r.Get("/{programName}", s.getProgram)
+					r.With(admin).Delete("/{programName}", s.deleteProgram)
+				})
+				configRouter.Route("/oven-config", func(r chi.Router) {
+					r.Get("/", s.getConfig)
+					r.With(admin).Post("/", s.updateConfig)
+				})
+				configRouter.Route("/move-runs-usb", func(r chi.Router) {
+					r.With(admin).Post("/", s.moveAllRunsToUsb)
+				})
 
+			})
 		})
 	})
 
+	if s.configuration.Diagnostics.RunAtStartup {
+		go func() {
+			if _, err := s.ovenProgramWorker.RunDiagnostics(false); err != nil {
+				s.logger.Error("Startup diagnostics not done", "error", err)
+			}
+		}()
+	}
+
 	s.initialized = true
 }
 
+// initAuth reads the users and starts the sessions. The authentication settings are read only here, editing
+// them in the configuration needs a restart.
+func (s *MachineServer) initAuth() {
+	authConfig := s.configuration.Auth
+	var err error
+	s.users, err = auth.NewStore(authConfig.UsersFile)
+	if err != nil {
+		s.logger.Error("Error", "error", err)
+		panic("cannot read the users")
+	}
+	s.sessions = auth.NewSessions(time.Duration(authConfig.SessionHours * float64(time.Hour)))
+	s.loginThrottle = auth.NewLoginThrottle(time.Second, 5*time.Minute)
+	s.authEnabled = authConfig.Enabled
+	if s.authEnabled && s.users.Empty() {
+		s.logger.Warn("Authentication enabled without users, nobody can log in: add an admin with -set-user", "users-file", authConfig.UsersFile)
+	}
+	if !s.authEnabled {
+		s.logger.Warn("Authentication disabled, everybody can control the oven")
+	}
+}
+
+// setConfiguration keeps the configuration of the file and sets the one in use, with the server options applied
+func (s *MachineServer) setConfiguration(c config.Config) {
+	s.fileConfiguration = &c
+	effective := c
+	s.applyDataDirectories(&effective)
+	s.configuration = &effective
+}
+
+// applyDataDirectories overrides the data folders of the configuration with the ones set in the server options
+func (s *MachineServer) applyDataDirectories(c *config.Config) {
+	if s.programsDirectory != "" {
+		c.Server.OvenProgramFolder = s.programsDirectory
+	}
+	if s.runsDirectory != "" {
+		c.Controller.SavedRunFolder = s.runsDirectory
+	}
+}
+
+// sameOrigin allows only the pages served from the same host and port of the API
+func sameOrigin(r *http.Request, origin string) bool {
+	return origin == "http://"+r.Host || origin == "https://"+r.Host
+}
+
 func (s *MachineServer) updateMachineFromConfig() {
 	s.machine.InitConfig(*s.configuration)
 }
@@ -169,7 +345,7 @@ func (s MachineServer) FileServer(router *chi.Mux, root string) {
 }
 
 func NewMachineServer(options ...func(*MachineServer)) *MachineServer {
-	machineServer := &MachineServer{}
+	machineServer := &MachineServer{configFile: "configuration.yaml"}
 	for _, o := range options {
 		o(machineServer)
 	}
@@ -6,12 +6,22 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
 	"github.com/idalmasso/ovencontrol/backend/ovenprograms"
 )
 
+// getAllDataActualWork returns the points of the actual run, every step-th or downsampled to max-points
 func (s *MachineServer) getAllDataActualWork(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("getAllDataActualWork called")
+	maxPoints, ok := maxPointsParam(w, r)
+	if !ok {
+		return
+	}
 	w.WriteHeader(http.StatusOK)
+	if maxPoints > 0 {
+		json.NewEncoder(w).Encode(s.ovenProgramWorker.GetProcessData(0, 0, 0, maxPoints).Points)
+		return
+	}
 	step := 1
 	var err error
 	if r.URL.Query().Get("step") != "" {
@@ -22,18 +32,82 @@ func (s *MachineServer) getAllDataActualWork(w http.ResponseWriter, r *http.Requ
 	}
 	json.NewEncoder(w).Encode(s.ovenProgramWorker.GetAllDataActualWork(step))
 }
+
+// getProcessData returns the points of the actual run incrementally: since is the next value of the previous
+// response, from and to limit the seconds from start, max-points downsamples keeping the shape of the curve.
+// When run changes a new run has started and the client must reload from 0.
+func (s *MachineServer) getProcessData(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getProcessData called")
+	maxPoints, ok := maxPointsParam(w, r)
+	if !ok {
+		return
+	}
+	var since int
+	var from, to float64
+	var err error
+	query := r.URL.Query()
+	if v := query.Get("since"); v != "" {
+		if since, err = strconv.Atoi(v); err != nil || since < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(struct{ Error string }{Error: "Invalid since"})
+			return
+		}
+	}
+	for name, value := range map[string]*float64{"from": &from, "to": &to} {
+		if v := query.Get(name); v != "" {
+			if *value, err = strconv.ParseFloat(v, 64); err != nil || *value < 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct{ Error string }{Error: "Invalid " + name})
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.ovenProgramWorker.GetProcessData(since, from, to, maxPoints))
+}
 func (s *MachineServer) isWorking(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("isWorking called")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(struct {
 		IsWorking   bool   `json:"is-working"`
+		IsPaused    bool   `json:"is-paused"`
+		CoolingDown bool   `json:"cooling-down"`
 		ProgramName string `json:"program-name"`
 	}{
 		IsWorking:   s.ovenProgramWorker.IsWorking(),
+		IsPaused:    s.ovenProgramWorker.IsPaused(),
+		CoolingDown: s.ovenProgramWorker.IsCoolingDown(),
 		ProgramName: s.ovenProgramWorker.GetRunningProgram(),
 	})
 }
 
+func (s *MachineServer) getIndicator(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getIndicator called")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		State commoninterface.IndicatorState `json:"state"`
+	}{State: s.machine.GetIndicatorState()})
+}
+
+// acknowledge silences the buzzer at the end of a program or after a fault
+func (s *MachineServer) acknowledge(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("acknowledge called")
+	s.ovenProgramWorker.AcknowledgeIndicator()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *MachineServer) getInterlocks(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getInterlocks called")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		commoninterface.InterlockState
+		IsPaused bool `json:"is-paused"`
+	}{
+		InterlockState: s.machine.GetInterlocks(),
+		IsPaused:       s.ovenProgramWorker.IsPaused(),
+	})
+}
+
 func (s *MachineServer) stopProgram(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("stopProgram called")
 	if s.ovenProgramWorker.IsWorking() {
@@ -49,6 +123,9 @@ func (s *MachineServer) stopProgram(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *MachineServer) testRamp(w http.ResponseWriter, r *http.Request) {
+	if s.diagnosticsBlockStart(w, r) {
+		return
+	}
 	if ok := s.tryStartTestRamp(s.configuration.Server.TestRampTemperature, s.configuration.Server.TestRampTimeMinutes); !ok {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(struct{ Error string }{Error: "Machine is working"})
@@ -65,6 +142,9 @@ func (s *MachineServer) tryStartTestRamp(temperature, timeMinutes float64) bool
 }
 
 func (s *MachineServer) setPowerOneMinute(w http.ResponseWriter, r *http.Request) {
+	if s.diagnosticsBlockStart(w, r) {
+		return
+	}
 	var power struct {
 		Power float64 `json:"power,string"`
 	}
@@ -93,6 +173,9 @@ func (s *MachineServer) startProgram(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(struct{ Error string }{Error: "Machine is working"})
 		return
 	}
+	if s.diagnosticsBlockStart(w, r) {
+		return
+	}
 	programName := chi.URLParam(r, "programName")
 	if program, ok := s.ovenProgramManager.Programs()[programName]; ok {
 		s.ovenProgramWorker.StartOvenProgram(program, "")
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/idalmasso/ovencontrol/backend/ovenprograms"
+	"github.com/idalmasso/ovencontrol/backend/report"
+)
+
+func (s *MachineServer) getRuns(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getRuns called")
+	runs, err := s.ovenProgramWorker.GetRuns()
+	if err != nil {
+		s.logger.LogAttrs(r.Context(), slog.LevelError, "getRuns error", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(runs)
+}
+
+// maxPointsParam reads the max-points query parameter, 0 if not set. On error it writes the response and returns false.
+func maxPointsParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if r.URL.Query().Get("max-points") == "" {
+		return 0, true
+	}
+	maxPoints, err := strconv.Atoi(r.URL.Query().Get("max-points"))
+	if err != nil || maxPoints < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: "Invalid max-points"})
+		return 0, false
+	}
+	return maxPoints, true
+}
+
+// getRun returns the run description and its data points, at most max-points if set
+func (s *MachineServer) getRun(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getRun called")
+	runID := chi.URLParam(r, "runID")
+	maxPoints, ok := maxPointsParam(w, r)
+	if !ok {
+		return
+	}
+	info, err := s.ovenProgramWorker.GetRunInfo(runID)
+	if err != nil {
+		s.writeRunError(w, r, "getRun", err)
+		return
+	}
+	points, err := s.ovenProgramWorker.GetRun(runID, maxPoints)
+	if err != nil {
+		s.writeRunError(w, r, "getRun", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		ovenprograms.RunInfo
+		DataPoints ovenprograms.ProgramDataPointArray `json:"data-points"`
+	}{RunInfo: info, DataPoints: points})
+}
+
+// compareRuns returns the runs in the ids parameter (comma separated) aligned by time from start or by segment
+// (align parameter), with the statistics of each segment
+func (s *MachineServer) compareRuns(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("compareRuns called")
+	var ids []string
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: "No runs to compare"})
+		return
+	}
+	maxPoints, ok := maxPointsParam(w, r)
+	if !ok {
+		return
+	}
+	comparison, err := s.ovenProgramWorker.CompareRuns(ids, r.URL.Query().Get("align"), maxPoints)
+	if err != nil {
+		s.writeRunError(w, r, "compareRuns", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// downloadRun sends the raw run file as csv
+func (s *MachineServer) downloadRun(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("downloadRun called")
+	runID := chi.URLParam(r, "runID")
+	path, err := s.ovenProgramWorker.RunFilePath(runID)
+	if err != nil {
+		s.writeRunError(w, r, "downloadRun", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+runID+`.csv"`)
+	http.ServeFile(w, r, path)
+}
+
+// getRunReport renders the firing report of a run as an html page, as an attachment with download=true
+func (s *MachineServer) getRunReport(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getRunReport called")
+	runID := chi.URLParam(r, "runID")
+	info, err := s.ovenProgramWorker.GetRunInfo(runID)
+	if err != nil {
+		s.writeRunError(w, r, "getRunReport", err)
+		return
+	}
+	history, err := s.ovenProgramWorker.GetRun(runID, 0)
+	if err != nil {
+		s.writeRunError(w, r, "getRunReport", err)
+		return
+	}
+	alarms, err := s.ovenProgramWorker.GetRunAlarms(runID)
+	if err != nil {
+		s.writeRunError(w, r, "getRunReport", err)
+		return
+	}
+	notes, err := s.ovenProgramWorker.GetRunNotes(runID)
+	if err != nil {
+		s.writeRunError(w, r, "getRunReport", err)
+		return
+	}
+	maxPower := s.ovenProgramWorker.RunMaxPower(runID)
+	data := report.Data{
+		Info:        info,
+		History:     history,
+		Segments:    history.SegmentStats(maxPower),
+		EnergyKWh:   history.EnergyKWh(maxPower),
+		Alarms:      alarms,
+		Notes:       notes,
+		GeneratedAt: time.Now(),
+	}
+	if metadata, err := s.ovenProgramWorker.GetRunMetadata(runID); err == nil {
+		data.Program = &metadata.Program
+		data.ProgramSnapshot = true
+	} else if program, ok := s.ovenProgramManager.Programs()[info.ProgramName]; ok {
+		data.Program = &program
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.URL.Query().Get("download") == "true" {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+runID+`.html"`)
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := report.Render(w, data); err != nil {
+		s.logger.LogAttrs(r.Context(), slog.LevelError, "getRunReport error", slog.String("error", err.Error()))
+	}
+}
+
+func (s *MachineServer) getRunMetadata(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getRunMetadata called")
+	metadata, err := s.ovenProgramWorker.GetRunMetadata(chi.URLParam(r, "runID"))
+	if err != nil {
+		s.writeRunError(w, r, "getRunMetadata", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(metadata)
+}
+
+func (s *MachineServer) getRunAlarms(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getRunAlarms called")
+	alarms, err := s.ovenProgramWorker.GetRunAlarms(chi.URLParam(r, "runID"))
+	if err != nil {
+		s.writeRunError(w, r, "getRunAlarms", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(alarms)
+}
+
+func (s *MachineServer) getRunNotes(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getRunNotes called")
+	notes, err := s.ovenProgramWorker.GetRunNotes(chi.URLParam(r, "runID"))
+	if err != nil {
+		s.writeRunError(w, r, "getRunNotes", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notes)
+}
+
+func (s *MachineServer) setRunNotes(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("setRunNotes called")
+	var body struct {
+		Notes string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+		return
+	}
+	notes, err := s.ovenProgramWorker.SetRunNotes(chi.URLParam(r, "runID"), body.Notes)
+	if err != nil {
+		s.writeRunError(w, r, "setRunNotes", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notes)
+}
+
+func (s *MachineServer) deleteRun(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("deleteRun called")
+	if err := s.ovenProgramWorker.DeleteRun(chi.URLParam(r, "runID")); err != nil {
+		s.writeRunError(w, r, "deleteRun", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *MachineServer) writeRunError(w http.ResponseWriter, r *http.Request, handler string, err error) {
+	s.logger.LogAttrs(r.Context(), slog.LevelError, handler+" error", slog.String("error", err.Error()))
+	switch {
+	case errors.Is(err, ovenprograms.ErrRunNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: "Run not found"})
+	case errors.Is(err, ovenprograms.ErrInvalidAlignment):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+	case errors.Is(err, ovenprograms.ErrRunInProgress):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: "Run in progress"})
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+	}
+}
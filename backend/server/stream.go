@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
+	"github.com/idalmasso/ovencontrol/backend/events"
+)
+
+const (
+	streamPath          = "/api/processes/stream"
+	streamKeepAliveTime = 15 * time.Second
+)
+
+// alarmNotifier is a machine that can notify the alarms when they are raised
+type alarmNotifier interface {
+	SetAlarmHandler(handler func(commoninterface.Alarm))
+}
+
+// stream sends the events as Server-Sent Events. A client resumes from the last event received
+// with the Last-Event-ID header (sent by EventSource when reconnecting) or the since query parameter.
+// At connection the current state is sent, then the events.
+func (s *MachineServer) stream(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("stream called")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(struct{ Error string }{Error: "Streaming not supported"})
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("since")
+	}
+	var lastSeq uint64
+	if lastID != "" {
+		var err error
+		if lastSeq, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(struct{ Error string }{Error: "Invalid last event id"})
+			return
+		}
+	}
+	backlog, ch := s.events.Subscribe(lastSeq)
+	defer s.events.Unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if lastSeq == 0 {
+		//a new client receives the state, numbered as the last event so that it can resume from it
+		writeEvent(w, events.Event{Seq: s.events.LastSeq(), Time: time.Now(), Type: events.State, Data: s.ovenProgramWorker.GetState()})
+	}
+	for _, e := range backlog {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAliveTime)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				//too slow, the client reconnects and resumes
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+	return err
+}
+
+// timeoutExceptStream is middleware.Timeout for all the routes but the stream, that stays open
+func timeoutExceptStream(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withTimeout := middleware.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.TrimSuffix(r.URL.Path, "/") == streamPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
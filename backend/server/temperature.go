@@ -2,11 +2,15 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/idalmasso/ovencontrol/backend/commoninterface"
 )
 
 type temperatureReader interface {
 	GetTemperature() (float64, error)
+	GetColdJunctionTemperature() (float64, error)
 }
 
 func (s *MachineServer) getTemperature(w http.ResponseWriter, r *http.Request) {
@@ -20,10 +24,22 @@ func (s *MachineServer) getTemperature(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
+	var coldJunction *float64
+	if value, err := s.machine.GetColdJunctionTemperature(); err == nil {
+		coldJunction = &value
+	} else if !errors.Is(err, commoninterface.ErrNotSupported) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+		}{Error: err.Error()})
+
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(struct {
-		Temperature float64 `json:"oven-temperature"`
-	}{Temperature: temperature})
+		Temperature  float64  `json:"oven-temperature"`
+		ColdJunction *float64 `json:"cold-junction-temperature,omitempty"`
+	}{Temperature: temperature, ColdJunction: coldJunction})
 }
 
 func (s *MachineServer) getTemperaturesProcess(w http.ResponseWriter, r *http.Request) {
@@ -44,3 +60,12 @@ func (s *MachineServer) getTemperaturesProcess(w http.ResponseWriter, r *http.Re
 		TimeSeconds         float64 `json:"time-seconds"`
 	}{Temperature: temperature, ExpectedTemperature: s.ovenProgramWorker.GetTargetTemperature(), TimeSeconds: s.ovenProgramWorker.GetTimeSeconds()})
 }
+
+func (s *MachineServer) getAlarms(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("getAlarms called")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Alarms             []commoninterface.Alarm `json:"alarms"`
+		SensorTemperatures []float64               `json:"sensor-temperatures"`
+	}{Alarms: s.machine.GetAlarms(), SensorTemperatures: s.machine.GetSensorTemperatures()})
+}
@@ -2,6 +2,18 @@
 import { createRouter, createWebHistory } from "vue-router";
 
 const routes = [
+  {
+    path: "/login",
+    component: () => import("@/layouts/default/DefaultLayout.vue"),
+    children: [
+      {
+        path: "",
+        name: "Login",
+        component: () => import("@/views/LoginView.vue"),
+        meta: { title: "ACCESSO" },
+      },
+    ],
+  },
   {
     path: "/",
     component: () => import("@/layouts/default/DefaultLayout.vue"),
@@ -221,4 +233,15 @@ const router = createRouter({
   routes,
 });
 
+// without a login every page but the login one goes to the login
+router.beforeEach((to) => {
+  if (to.name === "Login") {
+    return true;
+  }
+  return fetch("http://localhost:3333/api/auth/me")
+    .then((a) => a.json())
+    .then((status) => (status["enabled"] && !status["user"] ? { name: "Login" } : true))
+    .catch(() => true);
+});
+
 export default router;